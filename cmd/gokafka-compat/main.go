@@ -0,0 +1,114 @@
+// Command gokafka-compat checks that a reader schema (.avsc) can read every
+// version registered for a subject, so breaking changes are caught before deploying.
+//
+//	gokafka-compat -subject users-value -schema user.avsc
+//
+// The registry is configured with the AVRO_SCHEMA_* envs, -registry overrides AVRO_SCHEMA_URL and AVRO_SCHEMA_DIR.
+// It exits with 1 when any version is incompatible and 2 when the check couldn't run.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/registry"
+)
+
+func main() {
+	subject := flag.String("subject", "", "subject to check, e.g: users-value")
+	schemaPath := flag.String("schema", "", "path of the reader .avsc schema")
+	registryUrl := flag.String("registry", "", "Schema Registry url, overrides AVRO_SCHEMA_URL and AVRO_SCHEMA_DIR")
+	flag.Parse()
+
+	if len(*subject) == 0 || len(*schemaPath) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	compatible, err := run(*subject, *schemaPath, *registryUrl)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if !compatible {
+		os.Exit(1)
+	}
+}
+
+func run(subject string, schemaPath string, registryUrl string) (bool, error) {
+	reader, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		return false, err
+	}
+
+	avroConfig, err := avroConfig(registryUrl)
+	if err != nil {
+		return false, err
+	}
+
+	provider, err := registry.NewProvider(avroConfig)
+	if err != nil {
+		return false, err
+	}
+
+	report, err := registry.CheckSubject(provider, subject, string(reader))
+	if err != nil {
+		return false, err
+	}
+
+	for _, version := range report.Versions {
+		if len(version.Incompatibilities) == 0 {
+			fmt.Printf("%s version %d (id %d): compatible\n", subject, version.Version, version.ID)
+			continue
+		}
+
+		fmt.Printf("%s version %d (id %d): %d incompatible changes\n", subject, version.Version, version.ID, len(version.Incompatibilities))
+		for _, incompatibility := range version.Incompatibilities {
+			fmt.Printf("  %s\n", incompatibility)
+		}
+	}
+
+	return report.Compatible(), nil
+}
+
+// avroConfig reads the AVRO_SCHEMA_* envs, the registry url, when given, taking the
+// place of both AVRO_SCHEMA_URL and AVRO_SCHEMA_DIR.
+func avroConfig(registryUrl string) (config.EnvAvroConfig, error) {
+	avroConfig, err := config.GetAvro()
+	if len(registryUrl) == 0 {
+		return avroConfig, err
+	}
+
+	avroConfig.Url = registryUrl
+	avroConfig.Dir = ""
+
+	var validationErrors config.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		if errors.Is(err, config.ErrInvalidUrl) {
+			return avroConfig, nil
+		}
+
+		return avroConfig, err
+	}
+
+	var errs config.ValidationErrors
+	for _, err := range validationErrors {
+		if !errors.Is(err, config.ErrInvalidUrl) {
+			errs = append(errs, err)
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return avroConfig, nil
+	case 1:
+		return avroConfig, errs[0]
+	}
+
+	return avroConfig, errs
+}
@@ -0,0 +1,114 @@
+// Command gokafka-offsets moves the committed offsets of a consumer group, so a
+// time range can be processed again, e.g: the last 6 hours after a downstream bug.
+//
+//	gokafka-offsets -group orders-consumer -topics orders -ago 6h -dry-run
+//
+// The brokers are configured with the KAFKA_* envs, -group and -topics override
+// KAFKA_GROUP and KAFKA_TOPICS. The group must have no active members.
+// It exits with 1 when the offsets couldn't be moved and 2 on wrong flags.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/offsets"
+)
+
+func main() {
+	group := flag.String("group", "", "consumer group, overrides KAFKA_GROUP")
+	topics := flag.String("topics", "", "comma separated topics, overrides KAFKA_TOPICS")
+	toTime := flag.String("to-time", "", "moves to the first messages since this RFC3339 timestamp")
+	ago := flag.Duration("ago", 0, "moves to the first messages since this duration ago, e.g: 6h")
+	toOffset := flag.Int64("to-offset", -1, "moves every partition to this offset")
+	shift := flag.Int64("shift", 0, "moves the committed offsets by this amount, backwards when negative")
+	dryRun := flag.Bool("dry-run", false, "only prints the planned offsets")
+	flag.Parse()
+
+	position, err := parsePosition(*toTime, *ago, *toOffset, *shift)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*group, *topics, position, *dryRun); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// parsePosition gives the position of the only one of the flags which was set.
+func parsePosition(toTime string, ago time.Duration, toOffset int64, shift int64) (offsets.Position, error) {
+	var positions []offsets.Position
+	if len(toTime) > 0 {
+		at, err := time.Parse(time.RFC3339, toTime)
+		if err != nil {
+			return offsets.Position{}, fmt.Errorf("invalid -to-time, expected a RFC3339 timestamp: %v", err)
+		}
+		positions = append(positions, offsets.ToTime(at))
+	}
+	if ago > 0 {
+		positions = append(positions, offsets.ToTime(time.Now().Add(-ago)))
+	}
+	if toOffset >= 0 {
+		positions = append(positions, offsets.ToOffset(toOffset))
+	}
+	if shift != 0 {
+		positions = append(positions, offsets.ShiftBy(shift))
+	}
+
+	if len(positions) != 1 {
+		return offsets.Position{}, fmt.Errorf("set exactly one of -to-time, -ago, -to-offset or -shift")
+	}
+
+	return positions[0], nil
+}
+
+func run(group string, topics string, position offsets.Position, dryRun bool) error {
+	options := []config.Option{config.FromEnv()}
+	if len(group) > 0 {
+		options = append(options, config.WithGroup(group))
+	}
+	if len(topics) > 0 {
+		options = append(options, config.WithTopics(strings.Split(topics, ",")...))
+	}
+
+	consumerConfig, err := config.New(options...)
+	if err != nil {
+		return err
+	}
+
+	client, err := sarama.NewClient(strings.Split(consumerConfig.Brokers, ","), consumerConfig.Kafka)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	moves, err := offsets.Plan(client, consumerConfig.ConsumerGroup, strings.Split(consumerConfig.Topic, ","), position)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s on %s:\n", consumerConfig.ConsumerGroup, position)
+	for _, move := range moves {
+		fmt.Printf("  %s\n", move)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err := offsets.Apply(client, consumerConfig.ConsumerGroup, moves); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d offsets moved\n", len(moves))
+
+	return nil
+}
@@ -1,31 +1,75 @@
 package config
 
-import "github.com/Shopify/sarama"
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+)
 
 type Config struct {
 	Kafka         *sarama.Config
 	Brokers       string
 	ConsumerGroup string
 	Topic         string
+	// StartTime, when set, is where partitions with no committed offset start from.
+	StartTime time.Time
 }
 
 type EnvKafkaConfig struct {
-	Brokers         string
-	Version         string
-	ConsumerGroup   string
-	Topic           string
-	Assignor        string
-	OldestFirst     bool
-	AuthType        string
-	AuthCa          string
-	AuthCertificate string
-	AuthKey         string
-	Username        string
-	Password        string
+	Brokers       string
+	Version       string
+	ConsumerGroup string
+	Topic         string
+	Assignor      string
+	OldestFirst   bool
+	// OffsetReset is where a new consumer group starts, earliest or latest, taking precedence over OldestFirst.
+	OffsetReset string
+	// StartAt starts a new consumer group at a RFC3339 timestamp, or a duration ago, e.g: 2h.
+	StartAt  string
+	AuthType string
+	// SecurityProtocol is the Kafka security.protocol, taking precedence over AuthType when set.
+	SecurityProtocol string
+	AuthCa           string
+	AuthCertificate  string
+	AuthKey          string
+	// The *File settings read the PEM contents from files instead,
+	// the client certificate being reloaded when its files change.
+	AuthCaFile            string
+	AuthCertificateFile   string
+	AuthKeyFile           string
+	Username              string
+	Password              string
+	SaslMechanism         string
+	OAuthTokenUrl         string
+	OAuthClientId         string
+	OAuthClientSecret     string
+	OAuthScopes           string
+	TlsServerName         string
+	TlsMinVersion         string
+	TlsInsecureSkipVerify bool
+	// TokenProvider, when given, is used on OAUTHBEARER instead of the OAuth* client credentials.
+	TokenProvider TokenProvider
+	// The Kerberos* settings are used on the GSSAPI mechanism, authenticating
+	// with the keytab when given, or else with the Password.
+	KerberosPrincipal   string
+	KerberosRealm       string
+	KerberosKeytab      string
+	KerberosConfig      string
+	KerberosServiceName string
+	KerberosDisableFast bool
 }
 
 type EnvAvroConfig struct {
-	Url      string
-	Username string
-	Password string
+	Url             string
+	Username        string
+	Password        string
+	Token           string
+	AuthCa          string
+	AuthCertificate string
+	AuthKey         string
+	Timeout         time.Duration
+	Retries         int
+	RetryBackoff    time.Duration
+	CacheDir        string
+	Dir             string
 }
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// kerberosAuthentication sets the GSSAPI mechanism up, loading the krb5.conf
+// and the keytab right away, so a wrong path fails before connecting.
+func kerberosAuthentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) error {
+	gssapi := sarama.GSSAPIConfig{
+		AuthType:           sarama.KRB5_USER_AUTH,
+		KerberosConfigPath: kafkaConfig.KerberosConfig,
+		ServiceName:        kafkaConfig.KerberosServiceName,
+		Username:           kafkaConfig.KerberosPrincipal,
+		Password:           kafkaConfig.Password,
+		Realm:              kafkaConfig.KerberosRealm,
+		DisablePAFXFAST:    kafkaConfig.KerberosDisableFast,
+	}
+	if len(kafkaConfig.KerberosKeytab) > 0 {
+		gssapi.AuthType = sarama.KRB5_KEYTAB_AUTH
+		gssapi.KeyTabPath = kafkaConfig.KerberosKeytab
+	}
+	if len(gssapi.KerberosConfigPath) == 0 {
+		gssapi.KerberosConfigPath = defaultKerberosConfig
+	}
+	if len(gssapi.ServiceName) == 0 {
+		gssapi.ServiceName = defaultKerberosServiceName
+	}
+
+	if _, err := sarama.NewKerberosClient(&gssapi); err != nil {
+		return errors.Wrap(err, "Error loading the Kerberos config")
+	}
+
+	saramaConfig.Net.SASL.User = ""
+	saramaConfig.Net.SASL.Password = ""
+	saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeGSSAPI
+	saramaConfig.Net.SASL.GSSAPI = gssapi
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKerberosFiles writes a krb5.conf pointing the EXAMPLE.COM realm to the given kdc
+// address, and a keytab for the kafka-client principal.
+func writeKerberosFiles(t *testing.T, kdc string) (string, string) {
+	dir := t.TempDir()
+
+	krb5Conf := filepath.Join(dir, "krb5.conf")
+	content := fmt.Sprintf(`[libdefaults]
+  default_realm = EXAMPLE.COM
+  dns_lookup_kdc = false
+  udp_preference_limit = 1
+
+[realms]
+  EXAMPLE.COM = {
+    kdc = %s
+  }
+`, kdc)
+	assert.Nil(t, os.WriteFile(krb5Conf, []byte(content), 0600))
+
+	kt := keytab.New()
+	assert.Nil(t, kt.AddEntry("kafka-client", "EXAMPLE.COM", "KafkaPassword", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96))
+	ktBytes, err := kt.Marshal()
+	assert.Nil(t, err)
+
+	ktPath := filepath.Join(dir, "kafka-client.keytab")
+	assert.Nil(t, os.WriteFile(ktPath, ktBytes, 0600))
+
+	return krb5Conf, ktPath
+}
+
+func TestShouldConfigureKerberosWithKeytab(t *testing.T) {
+	krb5Conf, ktPath := writeKerberosFiles(t, "127.0.0.1:88")
+	kafkaConfig := EnvKafkaConfig{
+		Version:           "2.1.1",
+		AuthType:          "sasl_plaintext",
+		SaslMechanism:     "gssapi",
+		KerberosPrincipal: "kafka-client",
+		KerberosRealm:     "EXAMPLE.COM",
+		KerberosKeytab:    ktPath,
+		KerberosConfig:    krb5Conf,
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeGSSAPI), saramaConfig.Net.SASL.Mechanism)
+	assert.Equal(t, sarama.KRB5_KEYTAB_AUTH, saramaConfig.Net.SASL.GSSAPI.AuthType)
+	assert.Equal(t, "kafka", saramaConfig.Net.SASL.GSSAPI.ServiceName)
+	assert.Equal(t, "kafka-client", saramaConfig.Net.SASL.GSSAPI.Username)
+	assert.Nil(t, saramaConfig.Validate())
+}
+
+func TestShouldConfigureKerberosWithPassword(t *testing.T) {
+	krb5Conf, _ := writeKerberosFiles(t, "127.0.0.1:88")
+	kafkaConfig, err := New(
+		WithBrokers("localhost:9092"),
+		WithGroup("group"),
+		WithTopics("topic"),
+		WithVersion("2.1.1"),
+		WithKerberosPassword("kafka-client", "EXAMPLE.COM", "KafkaPassword"),
+		WithKerberosConfig(krb5Conf, "kafka-broker"),
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sarama.KRB5_USER_AUTH, kafkaConfig.Kafka.Net.SASL.GSSAPI.AuthType)
+	assert.Equal(t, "KafkaPassword", kafkaConfig.Kafka.Net.SASL.GSSAPI.Password)
+	assert.Equal(t, "kafka-broker", kafkaConfig.Kafka.Net.SASL.GSSAPI.ServiceName)
+	assert.True(t, kafkaConfig.Kafka.Net.TLS.Enable)
+}
+
+func TestShouldFailOnMissingKeytab(t *testing.T) {
+	krb5Conf, _ := writeKerberosFiles(t, "127.0.0.1:88")
+	kafkaConfig := EnvKafkaConfig{
+		Version:           "2.1.1",
+		AuthType:          "sasl_plaintext",
+		SaslMechanism:     "GSSAPI",
+		KerberosPrincipal: "kafka-client",
+		KerberosRealm:     "EXAMPLE.COM",
+		KerberosKeytab:    filepath.Join(t.TempDir(), "missing.keytab"),
+		KerberosConfig:    krb5Conf,
+	}
+
+	err := ConfigureSarama(kafkaConfig, sarama.NewConfig())
+
+	assert.Error(t, err)
+}
+
+func TestShouldValidateKerberosConfig(t *testing.T) {
+	_, err := New(WithBrokers("localhost:9092"), WithGroup("group"), WithTopics("topic"), WithSASLMechanism("GSSAPI"), WithSecurityProtocol("SASL_PLAINTEXT"))
+
+	assert.ErrorIs(t, err, ErrInvalidAuthKerberos)
+	assert.NotErrorIs(t, err, ErrInvalidAuthSaslSsl)
+}
+
+func TestShouldLoginOnTheConfiguredKdc(t *testing.T) {
+	// A KDC stand-in, which only reads the AS-REQ and hangs up.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	requests := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		request := make([]byte, size)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		requests <- request
+	}()
+
+	krb5Conf, ktPath := writeKerberosFiles(t, listener.Addr().String())
+	kafkaConfig := EnvKafkaConfig{
+		Version:           "2.1.1",
+		AuthType:          "sasl_plaintext",
+		SaslMechanism:     "GSSAPI",
+		KerberosPrincipal: "kafka-client",
+		KerberosRealm:     "EXAMPLE.COM",
+		KerberosKeytab:    ktPath,
+		KerberosConfig:    krb5Conf,
+	}
+	saramaConfig := sarama.NewConfig()
+	assert.Nil(t, ConfigureSarama(kafkaConfig, saramaConfig))
+
+	client, err := sarama.NewKerberosClient(&saramaConfig.Net.SASL.GSSAPI)
+	assert.Nil(t, err)
+
+	assert.Error(t, client.Login())
+	select {
+	case request := <-requests:
+		assert.NotEmpty(t, request)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the KDC stand-in got no AS-REQ")
+	}
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv points to a YAML or JSON file read by GetKafka and GetAvro,
+// with the env variables layered on top of it.
+const ConfigFileEnv = "GOKAFKA_CONFIG_FILE"
+
+// Sources tells where each setting came from, by its env name, e.g:
+// Sources["KAFKA_BROKERS"] == "file:config/prod.yaml", "env" or "default".
+type Sources map[string]string
+
+// binding ties a setting to its env variable. On config files the same setting
+// is the env name without the section prefix, lower cased, e.g:
+// KAFKA_AUTHENTICATION_TYPE is authentication_type on the kafka section.
+type binding struct {
+	env     string
+	target  interface{}
+	invalid error
+}
+
+// sections maps each config file section to the prefix of its env variables.
+var sections = map[string]string{
+	"kafka": "KAFKA_",
+	"avro":  "AVRO_SCHEMA_",
+}
+
+func kafkaBindings(kafkaConfig *EnvKafkaConfig) []binding {
+	return []binding{
+		{env: "KAFKA_BROKERS", target: &kafkaConfig.Brokers},
+		{env: "KAFKA_VERSION", target: &kafkaConfig.Version},
+		{env: "KAFKA_GROUP", target: &kafkaConfig.ConsumerGroup},
+		{env: "KAFKA_TOPICS", target: &kafkaConfig.Topic},
+		{env: "KAFKA_ASSIGNOR", target: &kafkaConfig.Assignor},
+		{env: "KAFKA_OFFSET_RESET", target: &kafkaConfig.OffsetReset},
+		{env: "KAFKA_OFFSET_START_AT", target: &kafkaConfig.StartAt},
+		{env: "KAFKA_AUTHENTICATION_TYPE", target: &kafkaConfig.AuthType},
+		{env: "KAFKA_SECURITY_PROTOCOL", target: &kafkaConfig.SecurityProtocol},
+		{env: "KAFKA_AUTHENTICATION_CA", target: &kafkaConfig.AuthCa},
+		{env: "KAFKA_AUTHENTICATION_KEY", target: &kafkaConfig.AuthKey},
+		{env: "KAFKA_AUTHENTICATION_CERTIFICATE", target: &kafkaConfig.AuthCertificate},
+		{env: "KAFKA_AUTHENTICATION_CA_FILE", target: &kafkaConfig.AuthCaFile},
+		{env: "KAFKA_AUTHENTICATION_KEY_FILE", target: &kafkaConfig.AuthKeyFile},
+		{env: "KAFKA_AUTHENTICATION_CERTIFICATE_FILE", target: &kafkaConfig.AuthCertificateFile},
+		{env: "KAFKA_USERNAME", target: &kafkaConfig.Username},
+		{env: "KAFKA_PASSWORD", target: &kafkaConfig.Password},
+		{env: "KAFKA_SASL_MECHANISM", target: &kafkaConfig.SaslMechanism},
+		{env: "KAFKA_OAUTH_TOKEN_URL", target: &kafkaConfig.OAuthTokenUrl},
+		{env: "KAFKA_OAUTH_CLIENT_ID", target: &kafkaConfig.OAuthClientId},
+		{env: "KAFKA_OAUTH_CLIENT_SECRET", target: &kafkaConfig.OAuthClientSecret},
+		{env: "KAFKA_OAUTH_SCOPES", target: &kafkaConfig.OAuthScopes},
+		{env: "KAFKA_KERBEROS_PRINCIPAL", target: &kafkaConfig.KerberosPrincipal},
+		{env: "KAFKA_KERBEROS_REALM", target: &kafkaConfig.KerberosRealm},
+		{env: "KAFKA_KERBEROS_KEYTAB", target: &kafkaConfig.KerberosKeytab},
+		{env: "KAFKA_KERBEROS_CONFIG", target: &kafkaConfig.KerberosConfig},
+		{env: "KAFKA_KERBEROS_SERVICE_NAME", target: &kafkaConfig.KerberosServiceName},
+		{env: "KAFKA_KERBEROS_DISABLE_FAST", target: &kafkaConfig.KerberosDisableFast, invalid: ErrInvalidKerberosDisableFast},
+		{env: "KAFKA_TLS_SERVER_NAME", target: &kafkaConfig.TlsServerName},
+		{env: "KAFKA_TLS_MIN_VERSION", target: &kafkaConfig.TlsMinVersion},
+		{env: "KAFKA_TLS_INSECURE_SKIP_VERIFY", target: &kafkaConfig.TlsInsecureSkipVerify},
+	}
+}
+
+func avroBindings(avroConfig *EnvAvroConfig) []binding {
+	return []binding{
+		{env: "AVRO_SCHEMA_URL", target: &avroConfig.Url},
+		{env: "AVRO_SCHEMA_USERNAME", target: &avroConfig.Username},
+		{env: "AVRO_SCHEMA_PASSWORD", target: &avroConfig.Password},
+		{env: "AVRO_SCHEMA_TOKEN", target: &avroConfig.Token},
+		{env: "AVRO_SCHEMA_CA", target: &avroConfig.AuthCa},
+		{env: "AVRO_SCHEMA_CERTIFICATE", target: &avroConfig.AuthCertificate},
+		{env: "AVRO_SCHEMA_KEY", target: &avroConfig.AuthKey},
+		{env: "AVRO_SCHEMA_TIMEOUT", target: &avroConfig.Timeout, invalid: ErrInvalidAvroTimeout},
+		{env: "AVRO_SCHEMA_RETRIES", target: &avroConfig.Retries, invalid: ErrInvalidAvroRetries},
+		{env: "AVRO_SCHEMA_RETRY_BACKOFF", target: &avroConfig.RetryBackoff, invalid: ErrInvalidAvroBackoff},
+		{env: "AVRO_SCHEMA_CACHE_DIR", target: &avroConfig.CacheDir},
+		{env: "AVRO_SCHEMA_DIR", target: &avroConfig.Dir},
+	}
+}
+
+// set parses the value into the setting, leaving it untouched when it is invalid.
+func (binding binding) set(value string) (err error) {
+	switch target := binding.target.(type) {
+	case *string:
+		*target = value
+	case *bool:
+		var parsed bool
+		if parsed, err = strconv.ParseBool(value); err == nil {
+			*target = parsed
+		}
+	case *int:
+		var parsed int
+		if parsed, err = strconv.Atoi(value); err == nil {
+			*target = parsed
+		}
+	case *time.Duration:
+		var parsed time.Duration
+		if parsed, err = time.ParseDuration(value); err == nil {
+			*target = parsed
+		}
+	default:
+		err = fmt.Errorf("unsupported setting type %T", target)
+	}
+
+	if err != nil && binding.invalid != nil {
+		return binding.invalid
+	}
+
+	return errors.Wrapf(err, "invalid %s", binding.env)
+}
+
+// layer is one place settings are read from. The first layer having a
+// setting wins, so the env goes before the file.
+type layer interface {
+	lookup(env string) (value string, origin string, ok bool)
+}
+
+// envLayer reads the env variables, with the prefix in front of their names when given,
+// e.g: ORDERS_KAFKA_BROKERS for the ORDERS_ prefix.
+type envLayer struct {
+	prefix string
+}
+
+// Empty env variables count as not set, as os.Getenv always did.
+func (env envLayer) lookup(name string) (string, string, bool) {
+	value := os.Getenv(env.prefix + name)
+
+	return value, "env", len(value) > 0
+}
+
+type fileLayer struct {
+	path   string
+	values map[string]string
+}
+
+func (file *fileLayer) lookup(env string) (string, string, bool) {
+	value, ok := file.values[env]
+
+	return value, "file:" + file.path, ok
+}
+
+func layers(prefix string, path string) ([]layer, error) {
+	if len(path) == 0 {
+		return []layer{envLayer{prefix: prefix}}, nil
+	}
+
+	file, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []layer{envLayer{prefix: prefix}, file}, nil
+}
+
+func load(bindings []binding, layers []layer, sources Sources) error {
+	var errs []error
+	for _, binding := range bindings {
+		sources[binding.env] = "default"
+
+		for _, layer := range layers {
+			value, origin, ok := layer.lookup(binding.env)
+			if !ok {
+				continue
+			}
+
+			if err := binding.set(value); err != nil {
+				errs = append(errs, err)
+			}
+			sources[binding.env] = origin
+
+			break
+		}
+	}
+
+	return combine(errs...)
+}
+
+// readFile reads a YAML or JSON config file, with one section per prefix, e.g:
+//
+//	kafka:
+//	  brokers: localhost:9092
+//	  topics: [orders, payments]
+//	avro:
+//	  url: http://schema-registry
+func readFile(path string) (*fileLayer, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading config file")
+	}
+
+	var parsed map[string]map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &parsed)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &parsed)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, use .yaml, .yml or .json", path)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error parsing config file %s", path)
+	}
+
+	known := map[string]bool{}
+	for _, binding := range append(kafkaBindings(&EnvKafkaConfig{}), avroBindings(&EnvAvroConfig{})...) {
+		known[binding.env] = true
+	}
+
+	file := &fileLayer{path: path, values: map[string]string{}}
+	for section, settings := range parsed {
+		prefix, ok := sections[section]
+		if !ok {
+			return nil, fmt.Errorf("unknown section %s on config file %s", section, path)
+		}
+
+		for key, value := range settings {
+			env := prefix + strings.ToUpper(key)
+			if !known[env] {
+				return nil, fmt.Errorf("unknown setting %s.%s on config file %s", section, key, path)
+			}
+
+			file.values[env] = fileValue(value)
+		}
+	}
+
+	return file, nil
+}
+
+// fileValue turns a file value into its env form, lists become comma separated.
+func fileValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = fileValue(item)
+		}
+
+		return strings.Join(items, ",")
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unsetEnvs() {
+	for _, binding := range append(kafkaBindings(&EnvKafkaConfig{}), avroBindings(&EnvAvroConfig{})...) {
+		os.Unsetenv(binding.env)
+	}
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	dir, _ := ioutil.TempDir("", "config")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestLoadKafkaFromYamlWithEnvOverrides(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	path := writeConfigFile(t, "kafka.yaml", `
+kafka:
+  brokers: [broker-1:9092, broker-2:9092]
+  version: 2.1.1
+  group: file_group
+  topics: file_topic
+  assignor: range
+`)
+	os.Setenv("KAFKA_GROUP", "env_group")
+
+	kafkaConfig, sources, err := LoadKafka(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "broker-1:9092,broker-2:9092", kafkaConfig.Brokers)
+	assert.Equal(t, "2.1.1", kafkaConfig.Version)
+	assert.Equal(t, "env_group", kafkaConfig.ConsumerGroup)
+	assert.Equal(t, "file_topic", kafkaConfig.Topic)
+	assert.Equal(t, "file:"+path, sources["KAFKA_BROKERS"])
+	assert.Equal(t, "env", sources["KAFKA_GROUP"])
+	assert.Equal(t, "default", sources["KAFKA_USERNAME"])
+}
+
+func TestLoadAvroFromJson(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	path := writeConfigFile(t, "avro.json", `{"avro": {"url": "http://schema-registry", "timeout": "2s", "retries": 1}}`)
+
+	avroConfig, sources, err := LoadAvro(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "http://schema-registry", avroConfig.Url)
+	assert.Equal(t, 2*time.Second, avroConfig.Timeout)
+	assert.Equal(t, 1, avroConfig.Retries)
+	assert.Equal(t, defaultAvroRetryBackoff, avroConfig.RetryBackoff)
+	assert.Equal(t, "default", sources["AVRO_SCHEMA_RETRY_BACKOFF"])
+}
+
+func TestGetKafkaReadsConfigFileEnv(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	path := writeConfigFile(t, "kafka.yml", "kafka:\n  brokers: broker\n  topics: topic\n  group: group\n  version: 2.1.1\n")
+	os.Setenv(ConfigFileEnv, path)
+	defer os.Unsetenv(ConfigFileEnv)
+
+	kafkaConfig, err := GetKafka()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "broker", kafkaConfig.Brokers)
+}
+
+func TestLoadRefusesUnknownSettings(t *testing.T) {
+	unsetEnvs()
+	path := writeConfigFile(t, "kafka.yaml", "kafka:\n  brokerz: broker\n")
+
+	_, _, err := LoadKafka(path)
+
+	assert.EqualError(t, err, "unknown setting kafka.brokerz on config file "+path)
+}
@@ -3,19 +3,58 @@ package config
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/pkg/errors"
+	"strings"
+	"time"
 )
 
+// assignors are the consumer group partition assignors, by their KAFKA_ASSIGNOR name.
+var assignors = map[string]sarama.BalanceStrategy{
+	"sticky":     sarama.BalanceStrategySticky,
+	"roundrobin": sarama.BalanceStrategyRoundRobin,
+	"range":      sarama.BalanceStrategyRange,
+}
+
+// assignor gives the balance strategy of the KAFKA_ASSIGNOR name,
+// nil when no name is given, to keep the sarama default.
+func assignor(name string) (sarama.BalanceStrategy, error) {
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	if name == "cooperative-sticky" {
+		return nil, ErrUnsupportedAssignor
+	}
+
+	strategy, ok := assignors[name]
+	if !ok {
+		return nil, fmt.Errorf("%w, got %q", ErrInvalidAssignor, name)
+	}
+
+	return strategy, nil
+}
+
 func Make() (*Config, error) {
-	envKafkaConfig, err := GetKafka()
+	return New(FromEnv())
+}
+
+// MakeWithPrefix is Make reading the envs with the given prefix, so one process
+// can hold many Kafka configs, e.g: ORDERS_KAFKA_BROKERS for the ORDERS_ prefix.
+func MakeWithPrefix(prefix string) (*Config, error) {
+	return newWithPrefix(prefix, FromEnvWithPrefix(prefix))
+}
+
+func build(envKafkaConfig EnvKafkaConfig) (*Config, error) {
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(envKafkaConfig, saramaConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	saramaConfig := sarama.NewConfig()
-
-	err = ConfigureSarama(envKafkaConfig, saramaConfig)
+	startTime, err := startTime(envKafkaConfig.StartAt, time.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -25,6 +64,7 @@ func Make() (*Config, error) {
 		Brokers:       envKafkaConfig.Brokers,
 		ConsumerGroup: envKafkaConfig.ConsumerGroup,
 		Topic:         envKafkaConfig.Topic,
+		StartTime:     startTime,
 	}
 
 	return config, nil
@@ -33,74 +73,181 @@ func Make() (*Config, error) {
 func ConfigureSarama(kafkaConfig EnvKafkaConfig, saramaConfig *sarama.Config) error {
 	err := authentication(saramaConfig, kafkaConfig)
 	if err != nil {
-		return errors.Wrap(err, "Error parsing Kafka authentication: %v")
+		return errors.Wrap(err, "Error parsing Kafka authentication")
 	}
 
 	version, err := sarama.ParseKafkaVersion(kafkaConfig.Version)
 	if err != nil {
-		return errors.Wrap(err, "Error parsing Kafka version: %v")
+		return errors.Wrap(err, "Error parsing Kafka version")
 	}
 
 	saramaConfig.Version = version
 
-	switch kafkaConfig.Assignor {
-	case "sticky":
-		saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategySticky
-	case "roundrobin":
-		saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	case "range":
-		saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRange
-	default:
-		err = errors.New("Unrecognized consumer group partition assignor: %s")
+	strategy, err := assignor(kafkaConfig.Assignor)
+	if err != nil {
+		return err
+	}
+
+	if strategy != nil {
+		saramaConfig.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{strategy}
 	}
 
-	if kafkaConfig.OldestFirst {
+	switch strings.ToLower(kafkaConfig.OffsetReset) {
+	case "earliest":
 		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+	case "latest":
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	case "":
+		if kafkaConfig.OldestFirst {
+			saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+		}
+	default:
+		return fmt.Errorf("%w, got %q", ErrInvalidOffsetReset, kafkaConfig.OffsetReset)
 	}
 
 	return nil
 }
 
-func authentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) error {
-	if kafkaConfig.AuthType == "ssl" {
-		return sslAuthentication(saramaConfig, kafkaConfig)
+// startTime gives the time of the KAFKA_OFFSET_START_AT, either a RFC3339
+// timestamp or a duration before now. It is zero when none is given.
+func startTime(startAt string, now time.Time) (time.Time, error) {
+	if len(startAt) == 0 {
+		return time.Time{}, nil
 	}
 
-	if kafkaConfig.AuthType == "sasl_ssl" {
-		saslSslAuthentication(saramaConfig, kafkaConfig)
+	if timestamp, err := time.Parse(time.RFC3339, startAt); err == nil {
+		return timestamp, nil
 	}
 
-	return nil
+	ago, err := time.ParseDuration(startAt)
+	if err != nil || ago < 0 {
+		return time.Time{}, fmt.Errorf("%w, got %q", ErrInvalidStartAt, startAt)
+	}
+
+	return now.Add(-ago), nil
 }
 
-func saslSslAuthentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) {
+func authentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) error {
+	switch securityProtocol(kafkaConfig) {
+	case "SSL":
+		return sslAuthentication(saramaConfig, kafkaConfig)
+	case "SASL_SSL":
+		return saslSslAuthentication(saramaConfig, kafkaConfig)
+	case "SASL_PLAINTEXT":
+		return saslAuthentication(saramaConfig, kafkaConfig)
+	case "PLAINTEXT":
+		return nil
+	}
+
+	return fmt.Errorf("%w, got %q", ErrInvalidSecurityProtocol, kafkaConfig.SecurityProtocol)
+}
+
+func saslAuthentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) error {
 	saramaConfig.Net.SASL.User = kafkaConfig.Username
 	saramaConfig.Net.SASL.Password = kafkaConfig.Password
 	saramaConfig.Net.SASL.Handshake = true
 	saramaConfig.Net.SASL.Enable = true
-	saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
 
-	saramaConfig.Net.TLS.Enable = true
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: true,
-		ClientAuth:         0,
+	switch strings.ToUpper(kafkaConfig.SaslMechanism) {
+	case "", "PLAIN":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case "SCRAM-SHA-256":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = newScramClientGenerator(sha256Generator)
+	case "SCRAM-SHA-512":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = newScramClientGenerator(sha512Generator)
+	case "OAUTHBEARER":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		saramaConfig.Net.SASL.TokenProvider = tokenProvider(kafkaConfig)
+	case "GSSAPI":
+		return kerberosAuthentication(saramaConfig, kafkaConfig)
+	default:
+		return fmt.Errorf("%w, got %q", ErrInvalidSaslMechanism, kafkaConfig.SaslMechanism)
 	}
-	tlsConfig.InsecureSkipVerify = true
 
-	saramaConfig.Net.TLS.Config = tlsConfig
+	return nil
+}
+
+func tokenProvider(kafkaConfig EnvKafkaConfig) TokenProvider {
+	if kafkaConfig.TokenProvider != nil {
+		return kafkaConfig.TokenProvider
+	}
+
+	var scopes []string
+	if len(kafkaConfig.OAuthScopes) > 0 {
+		scopes = strings.Split(kafkaConfig.OAuthScopes, ",")
+	}
+
+	return NewClientCredentialsProvider(kafkaConfig.OAuthTokenUrl, kafkaConfig.OAuthClientId, kafkaConfig.OAuthClientSecret, scopes...)
+}
+
+func saslSslAuthentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) error {
+	if err := saslAuthentication(saramaConfig, kafkaConfig); err != nil {
+		return err
+	}
+
+	return sslAuthentication(saramaConfig, kafkaConfig)
 }
 
+// sslAuthentication turns TLS on, verifying the server with the given CA, or
+// the system ones, and authenticating with a client certificate when there is one.
 func sslAuthentication(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig) error {
-	tlsConfig, err := NewTLSConfig(
-		kafkaConfig.AuthCertificate,
-		kafkaConfig.AuthKey,
-		kafkaConfig.AuthCa)
+	ca, err := readPem(kafkaConfig.AuthCa, kafkaConfig.AuthCaFile)
 	if err != nil {
 		return err
 	}
 
-	// This can be used on test server if domain does not match cert:
-	tlsConfig.InsecureSkipVerify = true
+	tlsConfig := &tls.Config{}
+	switch {
+	case len(kafkaConfig.AuthCertificateFile) > 0:
+		reloader, err := NewCertificateReloader(kafkaConfig.AuthCertificateFile, kafkaConfig.AuthKeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	case len(kafkaConfig.AuthCertificate) > 0:
+		certificate, err := tls.X509KeyPair([]byte(kafkaConfig.AuthCertificate), []byte(kafkaConfig.AuthKey))
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if len(ca) > 0 {
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM([]byte(ca)) {
+			return errors.New("no valid certificate found on the Kafka CA")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	return enableTLS(saramaConfig, kafkaConfig, tlsConfig)
+}
+
+// tlsVersions are the accepted KAFKA_TLS_MIN_VERSION values.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// enableTLS turns TLS on with the server verification settings. The server
+// certificate and name are always verified, unless KAFKA_TLS_INSECURE_SKIP_VERIFY
+// is set, which should only be used on test clusters.
+func enableTLS(saramaConfig *sarama.Config, kafkaConfig EnvKafkaConfig, tlsConfig *tls.Config) error {
+	tlsConfig.MinVersion = tls.VersionTLS12
+	if len(kafkaConfig.TlsMinVersion) > 0 {
+		version, ok := tlsVersions[kafkaConfig.TlsMinVersion]
+		if !ok {
+			return fmt.Errorf("%w, got %q", ErrInvalidTlsMinVersion, kafkaConfig.TlsMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	tlsConfig.ServerName = kafkaConfig.TlsServerName
+	tlsConfig.InsecureSkipVerify = kafkaConfig.TlsInsecureSkipVerify
 
 	saramaConfig.Net.TLS.Enable = true
 	saramaConfig.Net.TLS.Config = tlsConfig
@@ -123,9 +270,10 @@ func NewTLSConfig(cert string, key string, ca string) (*tls.Config, error) {
 
 	// Load CA cert
 	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM([]byte(ca))
+	if !caCertPool.AppendCertsFromPEM([]byte(ca)) {
+		return &tlsConfig, errors.New("no valid certificate found on the CA")
+	}
 	tlsConfig.RootCAs = caCertPool
 
-	tlsConfig.BuildNameToCertificate()
-	return &tlsConfig, err
+	return &tlsConfig, nil
 }
@@ -1,9 +1,18 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"github.com/Shopify/sarama"
 	"github.com/stretchr/testify/assert"
+	"math/big"
 	"testing"
+	"time"
 )
 
 func TestShouldMakeKafkaConfig(t *testing.T) {
@@ -17,7 +26,269 @@ func TestShouldMakeKafkaConfig(t *testing.T) {
 
 	_ = ConfigureSarama(kafkaConfig, saramaConfig)
 
-	assert.Equal(t, sarama.BalanceStrategyRange, saramaConfig.Consumer.Group.Rebalance.Strategy)
+	assert.Equal(t, []sarama.BalanceStrategy{sarama.BalanceStrategyRange}, saramaConfig.Consumer.Group.Rebalance.GroupStrategies)
 	assert.Equal(t, "KafkaUsername", saramaConfig.Net.SASL.User)
 	assert.Equal(t, "KafkaPassword", saramaConfig.Net.SASL.Password)
 }
+
+func TestShouldFailOnUnknownAssignor(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:  "2.1.1",
+		Assignor: "ranged",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.ErrorIs(t, err, ErrInvalidAssignor)
+	assert.EqualError(t, err, ErrInvalidAssignor.Error()+`, got "ranged"`)
+}
+
+func TestShouldFailOnCooperativeStickyAssignor(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:  "2.1.1",
+		Assignor: "cooperative-sticky",
+	}
+
+	err := ConfigureSarama(kafkaConfig, sarama.NewConfig())
+
+	assert.ErrorIs(t, err, ErrUnsupportedAssignor)
+}
+
+func TestShouldSetStickyAssignor(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:  "2.1.1",
+		Assignor: "sticky",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []sarama.BalanceStrategy{sarama.BalanceStrategySticky}, saramaConfig.Consumer.Group.Rebalance.GroupStrategies)
+}
+
+func TestShouldConfigureScramOverPlaintext(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:       "2.1.1",
+		AuthType:      "sasl_plaintext",
+		SaslMechanism: "SCRAM-SHA-512",
+		Username:      "KafkaUsername",
+		Password:      "KafkaPassword",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.SASL.Enable)
+	assert.False(t, saramaConfig.Net.TLS.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), saramaConfig.Net.SASL.Mechanism)
+	assert.NotNil(t, saramaConfig.Net.SASL.SCRAMClientGeneratorFunc)
+	assert.Nil(t, saramaConfig.Validate())
+}
+
+func TestShouldConfigureScramOverTLS(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:       "2.1.1",
+		AuthType:      "sasl_ssl",
+		SaslMechanism: "scram-sha-256",
+		Username:      "KafkaUsername",
+		Password:      "KafkaPassword",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.TLS.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA256), saramaConfig.Net.SASL.Mechanism)
+}
+
+// newTestCertificate creates a self signed PEM certificate and key for the given name.
+func newTestCertificate(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	return string(certificate), string(privateKey)
+}
+
+func TestShouldVerifyTLSByDefault(t *testing.T) {
+	ca, _ := newTestCertificate(t, "kafka-ca")
+	kafkaConfig := EnvKafkaConfig{
+		Version:  "2.1.1",
+		AuthType: "sasl_ssl",
+		AuthCa:   ca,
+		Username: "KafkaUsername",
+		Password: "KafkaPassword",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.TLS.Enable)
+	assert.False(t, saramaConfig.Net.TLS.Config.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), saramaConfig.Net.TLS.Config.MinVersion)
+	assert.NotNil(t, saramaConfig.Net.TLS.Config.RootCAs)
+}
+
+func TestShouldSetTLSVerificationSettings(t *testing.T) {
+	certificate, key := newTestCertificate(t, "kafka-client")
+	kafkaConfig := EnvKafkaConfig{
+		Version:               "2.1.1",
+		AuthType:              "ssl",
+		AuthCa:                certificate,
+		AuthCertificate:       certificate,
+		AuthKey:               key,
+		TlsServerName:         "kafka.internal",
+		TlsMinVersion:         "1.3",
+		TlsInsecureSkipVerify: true,
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.Len(t, saramaConfig.Net.TLS.Config.Certificates, 1)
+	assert.Equal(t, "kafka.internal", saramaConfig.Net.TLS.Config.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), saramaConfig.Net.TLS.Config.MinVersion)
+	assert.True(t, saramaConfig.Net.TLS.Config.InsecureSkipVerify)
+}
+
+func TestShouldFailOnInvalidCA(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:  "2.1.1",
+		AuthType: "sasl_ssl",
+		AuthCa:   "not a certificate",
+		Username: "KafkaUsername",
+		Password: "KafkaPassword",
+	}
+
+	err := ConfigureSarama(kafkaConfig, sarama.NewConfig())
+
+	assert.Error(t, err)
+}
+
+func TestShouldConfigureSSLWithoutClientCertificate(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:          "2.1.1",
+		SecurityProtocol: "ssl",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.TLS.Enable)
+	assert.False(t, saramaConfig.Net.SASL.Enable)
+	assert.Empty(t, saramaConfig.Net.TLS.Config.Certificates)
+	assert.Nil(t, saramaConfig.Net.TLS.Config.RootCAs)
+}
+
+func TestShouldConfigureSaslPlaintextFromSecurityProtocol(t *testing.T) {
+	kafkaConfig := EnvKafkaConfig{
+		Version:          "2.1.1",
+		AuthType:         "ssl",
+		SecurityProtocol: "SASL_PLAINTEXT",
+		Username:         "KafkaUsername",
+		Password:         "KafkaPassword",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.SASL.Enable)
+	assert.False(t, saramaConfig.Net.TLS.Enable)
+}
+
+func TestShouldConfigureSaslSslWithClientCertificate(t *testing.T) {
+	certificate, key := newTestCertificate(t, "kafka-client")
+	kafkaConfig := EnvKafkaConfig{
+		Version:          "2.1.1",
+		SecurityProtocol: "SASL_SSL",
+		AuthCertificate:  certificate,
+		AuthKey:          key,
+		Username:         "KafkaUsername",
+		Password:         "KafkaPassword",
+	}
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(kafkaConfig, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.True(t, saramaConfig.Net.SASL.Enable)
+	assert.Len(t, saramaConfig.Net.TLS.Config.Certificates, 1)
+}
+
+func TestShouldValidateSecurityProtocol(t *testing.T) {
+	_, err := New(WithBrokers("localhost:9092"), WithGroup("group"), WithTopics("topic"), WithSecurityProtocol("TLS"))
+
+	assert.ErrorIs(t, err, ErrInvalidSecurityProtocol)
+
+	_, err = New(WithBrokers("localhost:9092"), WithGroup("group"), WithTopics("topic"), WithVersion("2.1.1"), WithSecurityProtocol("SSL"))
+	assert.Nil(t, err)
+
+	_, err = New(WithBrokers("localhost:9092"), WithGroup("group"), WithTopics("topic"), WithSecurityProtocol("SSL"), func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthCertificate = "certificate"
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrInvalidAuthSsl)
+}
+
+func TestShouldSetOffsetReset(t *testing.T) {
+	saramaConfig := sarama.NewConfig()
+
+	err := ConfigureSarama(EnvKafkaConfig{Version: "2.1.1", OldestFirst: true, OffsetReset: "latest"}, saramaConfig)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sarama.OffsetNewest, saramaConfig.Consumer.Offsets.Initial)
+}
+
+func TestShouldResolveStartTime(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	ago, err := startTime("2h", now)
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), ago)
+
+	timestamp, err := startTime("2026-10-18T08:30:00Z", now)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2026, 10, 18, 8, 30, 0, 0, time.UTC), timestamp)
+
+	_, err = startTime("yesterday", now)
+	assert.ErrorIs(t, err, ErrInvalidStartAt)
+}
+
+func TestShouldValidateOffsetSettings(t *testing.T) {
+	_, err := New(WithBrokers("localhost:9092"), WithGroup("group"), WithTopics("topic"), WithOffsetReset("beginning"), func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.StartAt = "yesterday"
+		return nil
+	})
+
+	assert.ErrorIs(t, err, ErrInvalidOffsetReset)
+	assert.ErrorIs(t, err, ErrInvalidStartAt)
+
+	kafkaConfig, err := New(WithBrokers("localhost:9092"), WithGroup("group"), WithTopics("topic"), WithVersion("2.1.1"), WithStartAgo(time.Hour))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), kafkaConfig.StartTime, time.Minute)
+}
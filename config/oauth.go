@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// TokenProvider gives the tokens sent on the OAUTHBEARER mechanism.
+// Token is called on every broker connection, so it should reuse tokens until they expire.
+type TokenProvider interface {
+	Token() (*sarama.AccessToken, error)
+}
+
+// tokenRefreshMargin is how long before expiring a token is refreshed,
+// so a connection is never started with a token about to expire.
+const tokenRefreshMargin = 30 * time.Second
+
+// ClientCredentialsProvider is a TokenProvider fetching tokens with the
+// OAuth client credentials grant, caching each one until close to its expiry.
+type ClientCredentialsProvider struct {
+	TokenUrl     string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+	HttpClient   *http.Client
+
+	lock   sync.Mutex
+	token  *sarama.AccessToken
+	expiry time.Time
+}
+
+func NewClientCredentialsProvider(tokenUrl string, clientId string, clientSecret string, scopes ...string) *ClientCredentialsProvider {
+	return &ClientCredentialsProvider{
+		TokenUrl:     tokenUrl,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		HttpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (provider *ClientCredentialsProvider) Token() (*sarama.AccessToken, error) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	if provider.token != nil && time.Now().Before(provider.expiry.Add(-tokenRefreshMargin)) {
+		return provider.token, nil
+	}
+
+	token, expiresIn, err := provider.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	provider.token = &sarama.AccessToken{Token: token}
+	provider.expiry = time.Now().Add(expiresIn)
+
+	return provider.token, nil
+}
+
+func (provider *ClientCredentialsProvider) fetch() (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(provider.Scopes) > 0 {
+		form.Set("scope", strings.Join(provider.Scopes, " "))
+	}
+
+	request, err := http.NewRequest(http.MethodPost, provider.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, errors.Wrap(err, "Error creating OAuth token request")
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(provider.ClientId), url.QueryEscape(provider.ClientSecret))
+
+	response, err := provider.HttpClient.Do(request)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "Error requesting OAuth token")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("OAuth token request failed with %s", response.Status)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", 0, errors.Wrap(err, "Error parsing OAuth token response")
+	}
+
+	if len(body.AccessToken) == 0 {
+		return "", 0, errors.New("OAuth token response has no access_token")
+	}
+
+	// Tokens without expires_in are kept for a short while, to still be refreshed.
+	expiresIn := time.Duration(body.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 5 * time.Minute
+	}
+
+	return body.AccessToken, expiresIn, nil
+}
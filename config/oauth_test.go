@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTokenServer(t *testing.T, calls *int, expiresIn int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		username, password, _ := r.BasicAuth()
+		r.ParseForm()
+
+		assert.Equal(t, "client-id", username)
+		assert.Equal(t, "client-secret", password)
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "kafka.read kafka.write", r.PostForm.Get("scope"))
+
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d}`, *calls, expiresIn)
+	}))
+}
+
+func TestClientCredentialsProviderCachesToken(t *testing.T) {
+	calls := 0
+	server := newTokenServer(t, &calls, 3600)
+	defer server.Close()
+
+	provider := NewClientCredentialsProvider(server.URL, "client-id", "client-secret", "kafka.read", "kafka.write")
+
+	first, err := provider.Token()
+	assert.Nil(t, err)
+	second, err := provider.Token()
+	assert.Nil(t, err)
+
+	assert.Equal(t, "token-1", first.Token)
+	assert.Equal(t, "token-1", second.Token)
+	assert.Equal(t, 1, calls)
+}
+
+func TestClientCredentialsProviderRefreshesExpiringToken(t *testing.T) {
+	calls := 0
+	server := newTokenServer(t, &calls, 10)
+	defer server.Close()
+
+	provider := NewClientCredentialsProvider(server.URL, "client-id", "client-secret", "kafka.read", "kafka.write")
+
+	provider.Token()
+	token, err := provider.Token()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "token-2", token.Token)
+}
+
+func TestClientCredentialsProviderFailsOnRejectedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewClientCredentialsProvider(server.URL, "client-id", "wrong").Token()
+
+	assert.Error(t, err)
+}
+
+type staticTokenProvider struct{}
+
+func (staticTokenProvider) Token() (*sarama.AccessToken, error) {
+	return &sarama.AccessToken{Token: "static"}, nil
+}
+
+func TestNewConfiguresOAuthBearer(t *testing.T) {
+	config, err := New(
+		WithBrokers("broker"),
+		WithVersion("2.1.1"),
+		WithGroup("group"),
+		WithTopics("topic"),
+		WithTokenProvider(staticTokenProvider{}),
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), config.Kafka.Net.SASL.Mechanism)
+	assert.Equal(t, staticTokenProvider{}, config.Kafka.Net.SASL.TokenProvider)
+	assert.Nil(t, config.Kafka.Validate())
+}
+
+func TestNewRefusesOAuthBearerWithoutCredentials(t *testing.T) {
+	_, err := New(
+		WithBrokers("broker"),
+		WithGroup("group"),
+		WithTopics("topic"),
+		WithOAuthClientCredentials("", "client-id", ""),
+	)
+
+	assert.ErrorIs(t, err, ErrInvalidAuthOAuth)
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Option sets up the Kafka settings used by New. Options are applied in order,
+// so the later ones override what the former have set.
+type Option func(kafkaConfig *EnvKafkaConfig) error
+
+// New builds the Config from the given options, resolving the secret references
+// on the credentials with the DefaultSecrets, e.g:
+//
+//	config.New(config.WithBrokers("localhost:9092"), config.WithGroup("group"), config.WithTopics("topic"))
+func New(options ...Option) (*Config, error) {
+	kafkaConfig := EnvKafkaConfig{
+		OldestFirst: true,
+	}
+
+	var errs []error
+	for _, option := range options {
+		errs = append(errs, option(&kafkaConfig))
+	}
+	errs = append(errs, resolveKafkaSecrets(&kafkaConfig))
+
+	if err := combine(append(errs, validateKafka(kafkaConfig))...); err != nil {
+		return nil, err
+	}
+
+	return build(kafkaConfig)
+}
+
+// newWithPrefix is New telling, on validation errors, which env prefix was used.
+func newWithPrefix(prefix string, options ...Option) (*Config, error) {
+	config, err := New(options...)
+
+	return config, prefixed(prefix, err)
+}
+
+// FromEnv reads the settings set on the KAFKA_* envs, and on the GOKAFKA_CONFIG_FILE when there is one.
+func FromEnv() Option {
+	return FromEnvWithPrefix("")
+}
+
+// FromEnvWithPrefix is FromEnv reading the envs with the given prefix, e.g: ORDERS_KAFKA_BROKERS.
+func FromEnvWithPrefix(prefix string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		fileLayers, err := layers(prefix, os.Getenv(prefix+ConfigFileEnv))
+		if err != nil {
+			return err
+		}
+
+		return load(kafkaBindings(kafkaConfig), fileLayers, Sources{})
+	}
+}
+
+// FromFile reads the settings set on the kafka section of a YAML or JSON file.
+func FromFile(path string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		file, err := readFile(path)
+		if err != nil {
+			return err
+		}
+
+		return load(kafkaBindings(kafkaConfig), []layer{file}, Sources{})
+	}
+}
+
+func WithBrokers(brokers ...string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.Brokers = strings.Join(brokers, ",")
+		return nil
+	}
+}
+
+func WithVersion(version string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.Version = version
+		return nil
+	}
+}
+
+func WithGroup(group string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.ConsumerGroup = group
+		return nil
+	}
+}
+
+func WithTopics(topics ...string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.Topic = strings.Join(topics, ",")
+		return nil
+	}
+}
+
+// WithAssignor sets the partition assignor: range, roundrobin or sticky.
+func WithAssignor(assignor string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.Assignor = assignor
+		return nil
+	}
+}
+
+// WithOffsetReset sets where a new consumer group starts, earliest or latest.
+func WithOffsetReset(offsetReset string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.OffsetReset = offsetReset
+		return nil
+	}
+}
+
+// WithStartTime starts a new consumer group at the first messages since the given time.
+func WithStartTime(startTime time.Time) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.StartAt = startTime.Format(time.RFC3339)
+		return nil
+	}
+}
+
+// WithStartAgo starts a new consumer group at the first messages since the given duration ago.
+func WithStartAgo(ago time.Duration) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.StartAt = ago.String()
+		return nil
+	}
+}
+
+// WithOldestFirst tells if a new consumer group starts from the oldest offset, which is the default.
+func WithOldestFirst(oldestFirst bool) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.OldestFirst = oldestFirst
+		return nil
+	}
+}
+
+// WithSecurityProtocol sets the Kafka security.protocol: PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL.
+// SSL only verifies the server unless a client certificate is given too, e.g: WithSSL.
+func WithSecurityProtocol(protocol string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.SecurityProtocol = protocol
+		return nil
+	}
+}
+
+// WithSSL authenticates with the given PEM client certificate, key and CA.
+func WithSSL(certificate string, key string, ca string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "ssl"
+		kafkaConfig.AuthCertificate = certificate
+		kafkaConfig.AuthKey = key
+		kafkaConfig.AuthCa = ca
+		return nil
+	}
+}
+
+// WithSSLFiles authenticates with the PEM client certificate, key and CA read from
+// the given files, reloading the certificate when its files change.
+func WithSSLFiles(certificateFile string, keyFile string, caFile string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "ssl"
+		kafkaConfig.AuthCertificateFile = certificateFile
+		kafkaConfig.AuthKeyFile = keyFile
+		kafkaConfig.AuthCaFile = caFile
+		return nil
+	}
+}
+
+// WithSASL authenticates with the given username and password over TLS.
+func WithSASL(username string, password string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "sasl_ssl"
+		kafkaConfig.Username = username
+		kafkaConfig.Password = password
+		return nil
+	}
+}
+
+// WithSASLMechanism sets the SASL mechanism: PLAIN, which is the default, SCRAM-SHA-256, SCRAM-SHA-512, OAUTHBEARER or GSSAPI.
+func WithSASLMechanism(mechanism string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.SaslMechanism = mechanism
+		return nil
+	}
+}
+
+// WithTokenProvider authenticates with OAUTHBEARER over TLS, using the tokens of the given provider.
+func WithTokenProvider(provider TokenProvider) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "sasl_ssl"
+		kafkaConfig.SaslMechanism = "OAUTHBEARER"
+		kafkaConfig.TokenProvider = provider
+		return nil
+	}
+}
+
+// WithOAuthClientCredentials authenticates with OAUTHBEARER over TLS, fetching
+// tokens from tokenUrl with the OAuth client credentials grant.
+func WithOAuthClientCredentials(tokenUrl string, clientId string, clientSecret string, scopes ...string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "sasl_ssl"
+		kafkaConfig.SaslMechanism = "OAUTHBEARER"
+		kafkaConfig.OAuthTokenUrl = tokenUrl
+		kafkaConfig.OAuthClientId = clientId
+		kafkaConfig.OAuthClientSecret = clientSecret
+		kafkaConfig.OAuthScopes = strings.Join(scopes, ",")
+		return nil
+	}
+}
+
+// WithKerberosKeytab authenticates with GSSAPI over TLS, as the principal of the given keytab file.
+func WithKerberosKeytab(principal string, realm string, keytab string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "sasl_ssl"
+		kafkaConfig.SaslMechanism = "GSSAPI"
+		kafkaConfig.KerberosPrincipal = principal
+		kafkaConfig.KerberosRealm = realm
+		kafkaConfig.KerberosKeytab = keytab
+		return nil
+	}
+}
+
+// WithKerberosPassword authenticates with GSSAPI over TLS, as the given principal and password.
+func WithKerberosPassword(principal string, realm string, password string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthType = "sasl_ssl"
+		kafkaConfig.SaslMechanism = "GSSAPI"
+		kafkaConfig.KerberosPrincipal = principal
+		kafkaConfig.KerberosRealm = realm
+		kafkaConfig.Password = password
+		return nil
+	}
+}
+
+// WithKerberosConfig sets the krb5.conf path, /etc/krb5.conf by default,
+// and the brokers service name, kafka by default.
+func WithKerberosConfig(path string, serviceName string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.KerberosConfig = path
+		kafkaConfig.KerberosServiceName = serviceName
+		return nil
+	}
+}
+
+// WithCA verifies the brokers with the given PEM CA, instead of the system ones.
+func WithCA(ca string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.AuthCa = ca
+		return nil
+	}
+}
+
+// WithTLSServerName verifies the brokers certificates against the given name, instead of their host.
+func WithTLSServerName(serverName string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.TlsServerName = serverName
+		return nil
+	}
+}
+
+// WithTLSMinVersion sets the minimum TLS version: 1.0, 1.1, 1.2, which is the default, or 1.3.
+func WithTLSMinVersion(version string) Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.TlsMinVersion = version
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify turns off the brokers certificate verification, only meant for test clusters.
+func WithInsecureSkipVerify() Option {
+	return func(kafkaConfig *EnvKafkaConfig) error {
+		kafkaConfig.TlsInsecureSkipVerify = true
+		return nil
+	}
+}
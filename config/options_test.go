@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBuildsConfigFromOptions(t *testing.T) {
+	config, err := New(
+		WithBrokers("broker-1:9092", "broker-2:9092"),
+		WithVersion("2.1.1"),
+		WithGroup("test_group"),
+		WithTopics("topic-a", "topic-b"),
+		WithAssignor("roundrobin"),
+		WithSASL("KafkaUsername", "KafkaPassword"),
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "broker-1:9092,broker-2:9092", config.Brokers)
+	assert.Equal(t, "test_group", config.ConsumerGroup)
+	assert.Equal(t, "topic-a,topic-b", config.Topic)
+	assert.Equal(t, "2.1.1", config.Kafka.Version.String())
+	assert.Equal(t, []sarama.BalanceStrategy{sarama.BalanceStrategyRoundRobin}, config.Kafka.Consumer.Group.Rebalance.GroupStrategies)
+	assert.Equal(t, sarama.OffsetOldest, config.Kafka.Consumer.Offsets.Initial)
+	assert.Equal(t, "KafkaUsername", config.Kafka.Net.SASL.User)
+	assert.True(t, config.Kafka.Net.TLS.Enable)
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	_, err := New(WithBrokers("broker"), WithTopics("topic"), WithVersion("2.1.1"))
+
+	assert.Equal(t, ErrInvalidConsumerGroup, err)
+}
+
+func TestNewLayersEnvOnTopOfOptions(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	os.Setenv("KAFKA_GROUP", "env_group")
+
+	config, err := New(
+		WithBrokers("broker"),
+		WithVersion("2.1.1"),
+		WithGroup("code_group"),
+		WithTopics("topic"),
+		WithAssignor("range"),
+		FromEnv(),
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "env_group", config.ConsumerGroup)
+	assert.Equal(t, "broker", config.Brokers)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+var (
+	sha256Generator scram.HashGeneratorFcn = sha256.New
+	sha512Generator scram.HashGeneratorFcn = sha512.New
+)
+
+// scramClient is the sarama.SCRAMClient used on SCRAM-SHA-256 and SCRAM-SHA-512 mechanisms.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func newScramClientGenerator(generator scram.HashGeneratorFcn) func() sarama.SCRAMClient {
+	return func() sarama.SCRAMClient {
+		return &scramClient{HashGeneratorFcn: generator}
+	}
+}
+
+func (client *scramClient) Begin(userName, password, authzID string) (err error) {
+	client.Client, err = client.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	client.ClientConversation = client.Client.NewConversation()
+
+	return nil
+}
+
+func (client *scramClient) Step(challenge string) (string, error) {
+	return client.ClientConversation.Step(challenge)
+}
+
+func (client *scramClient) Done() bool {
+	return client.ClientConversation.Done()
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xdg-go/scram"
+)
+
+func TestScramClientAuthenticatesAgainstServer(t *testing.T) {
+	for _, generator := range []scram.HashGeneratorFcn{sha256Generator, sha512Generator} {
+		credentials, _ := generator.NewClient("KafkaUsername", "KafkaPassword", "")
+		stored := credentials.GetStoredCredentials(scram.KeyFactors{Salt: "some-salt", Iters: 4096})
+		server, _ := generator.NewServer(func(username string) (scram.StoredCredentials, error) {
+			return stored, nil
+		})
+		serverConversation := server.NewConversation()
+
+		client := newScramClientGenerator(generator)()
+		assert.Nil(t, client.Begin("KafkaUsername", "KafkaPassword", ""))
+
+		challenge := ""
+		for !client.Done() {
+			response, err := client.Step(challenge)
+			assert.Nil(t, err)
+			if client.Done() {
+				break
+			}
+
+			challenge, err = serverConversation.Step(response)
+			assert.Nil(t, err)
+		}
+
+		assert.True(t, serverConversation.Valid())
+	}
+}
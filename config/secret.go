@@ -0,0 +1,280 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SecretProvider resolves a secret reference into its value, e.g: the
+// contents of /run/secrets/kafka_pw for file:///run/secrets/kafka_pw.
+type SecretProvider interface {
+	Secret(ref string) (string, error)
+}
+
+// SecretProviderFunc is a SecretProvider made of a function.
+type SecretProviderFunc func(ref string) (string, error)
+
+func (provider SecretProviderFunc) Secret(ref string) (string, error) {
+	return provider(ref)
+}
+
+// FileSecrets resolves file:// references into the content of the file, without the trailing newline.
+var FileSecrets = SecretProviderFunc(func(ref string) (string, error) {
+	content, err := os.ReadFile(strings.TrimPrefix(ref, "file://"))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+})
+
+// EnvSecrets resolves env:// references into the value of the env, e.g: env://KAFKA_PW.
+var EnvSecrets = SecretProviderFunc(func(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, "env://")
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("the %s env is not set", name)
+	}
+
+	return value, nil
+})
+
+// HTTPSecretProvider resolves references on a Vault like HTTP endpoint, getting
+// <Url>/<path> for <scheme>://<path>, with the Token as a bearer token when given.
+// The response is either a JSON with the secret on "value", or the secret as text.
+type HTTPSecretProvider struct {
+	Url        string
+	Token      string
+	HttpClient *http.Client
+}
+
+func NewHTTPSecretProvider(url string, token string) *HTTPSecretProvider {
+	return &HTTPSecretProvider{
+		Url:        strings.TrimRight(url, "/"),
+		Token:      token,
+		HttpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (provider *HTTPSecretProvider) Secret(ref string) (string, error) {
+	path := ref
+	if index := strings.Index(ref, "://"); index >= 0 {
+		path = ref[index+3:]
+	}
+
+	request, err := http.NewRequest(http.MethodGet, provider.Url+"/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if len(provider.Token) > 0 {
+		request.Header.Set("Authorization", "Bearer "+provider.Token)
+	}
+
+	response, err := provider.HttpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secret endpoint answered %d", response.StatusCode)
+	}
+
+	var secret struct {
+		Value *string `json:"value"`
+	}
+	if json.Unmarshal(body, &secret) == nil && secret.Value != nil {
+		return *secret.Value, nil
+	}
+
+	return strings.TrimRight(string(body), "\r\n"), nil
+}
+
+// secretScheme matches the scheme of a reference, anything else is taken as a plain value.
+var secretScheme = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*)://`)
+
+// Secrets resolves references with the provider registered for their scheme,
+// file:// and env:// being there by default. Values with no registered scheme
+// are plain secrets, kept as they are.
+type Secrets struct {
+	lock      sync.Mutex
+	providers map[string]SecretProvider
+	resolved  map[string]string
+}
+
+func NewSecrets() *Secrets {
+	return &Secrets{
+		providers: map[string]SecretProvider{
+			"file": FileSecrets,
+			"env":  EnvSecrets,
+		},
+		resolved: map[string]string{},
+	}
+}
+
+// DefaultSecrets resolves the references found on the Kafka and Avro settings.
+var DefaultSecrets = NewSecrets()
+
+// RegisterSecretProvider resolves the references with the given scheme using
+// the provider, e.g: RegisterSecretProvider("vault", NewHTTPSecretProvider(url, token)).
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	DefaultSecrets.Register(scheme, provider)
+}
+
+func (secrets *Secrets) Register(scheme string, provider SecretProvider) {
+	secrets.lock.Lock()
+	defer secrets.lock.Unlock()
+
+	secrets.providers[strings.ToLower(scheme)] = provider
+}
+
+func (secrets *Secrets) provider(value string) SecretProvider {
+	match := secretScheme.FindStringSubmatch(value)
+	if match == nil {
+		return nil
+	}
+
+	secrets.lock.Lock()
+	defer secrets.lock.Unlock()
+
+	return secrets.providers[strings.ToLower(match[1])]
+}
+
+// Secret resolves the reference, or gives back the plain value.
+func (secrets *Secrets) Secret(value string) (string, error) {
+	provider := secrets.provider(value)
+	if provider == nil {
+		return value, nil
+	}
+
+	secret, err := provider.Secret(value)
+	if err != nil {
+		return "", err
+	}
+
+	secrets.lock.Lock()
+	secrets.resolved[value] = secret
+	secrets.lock.Unlock()
+
+	return secret, nil
+}
+
+// Watch resolves again every reference already resolved, on each interval until
+// the context is done, calling rotated with the ones whose value has changed.
+// It only tells about the rotation: the configs already built keep the previous values,
+// gokafka.WithSecretRotation rebuilds the config and restarts the consumer with it.
+func (secrets *Secrets) Watch(ctx context.Context, interval time.Duration, rotated func(ref string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, ref := range secrets.refresh() {
+				rotated(ref)
+			}
+		}
+	}
+}
+
+// refresh resolves the known references again, telling which ones changed.
+func (secrets *Secrets) refresh() []string {
+	secrets.lock.Lock()
+	known := make(map[string]string, len(secrets.resolved))
+	for ref, value := range secrets.resolved {
+		known[ref] = value
+	}
+	secrets.lock.Unlock()
+
+	var changed []string
+	for ref, value := range known {
+		secret, err := secrets.Secret(ref)
+		if err != nil {
+			log.Error().Err(err).Msg("Error refreshing a secret, keeping the previous one.")
+			continue
+		}
+
+		if secret != value {
+			changed = append(changed, ref)
+		}
+	}
+
+	return changed
+}
+
+// secretSetting is a setting which may hold a secret reference, by its env name.
+type secretSetting struct {
+	env   string
+	value *string
+}
+
+// resolveSecrets resolves the references on the given settings.
+func resolveSecrets(secrets *Secrets, settings []secretSetting) error {
+	var errs []error
+	for _, setting := range settings {
+		secret, err := secrets.Secret(*setting.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w on %s: %v", ErrInvalidSecret, setting.env, err))
+			continue
+		}
+
+		*setting.value = secret
+	}
+
+	return combine(errs...)
+}
+
+// resolveKafkaSecrets resolves the Kafka credentials. The TLS material given
+// as file:// references is read as the *File settings, to be reloaded on rotation.
+func resolveKafkaSecrets(kafkaConfig *EnvKafkaConfig) error {
+	files := []struct {
+		value *string
+		file  *string
+	}{
+		{&kafkaConfig.AuthCa, &kafkaConfig.AuthCaFile},
+		{&kafkaConfig.AuthCertificate, &kafkaConfig.AuthCertificateFile},
+		{&kafkaConfig.AuthKey, &kafkaConfig.AuthKeyFile},
+	}
+	for _, setting := range files {
+		if strings.HasPrefix(*setting.value, "file://") && len(*setting.file) == 0 {
+			*setting.file = strings.TrimPrefix(*setting.value, "file://")
+			*setting.value = ""
+		}
+	}
+
+	return resolveSecrets(DefaultSecrets, []secretSetting{
+		{"KAFKA_AUTHENTICATION_CA", &kafkaConfig.AuthCa},
+		{"KAFKA_AUTHENTICATION_CERTIFICATE", &kafkaConfig.AuthCertificate},
+		{"KAFKA_AUTHENTICATION_KEY", &kafkaConfig.AuthKey},
+		{"KAFKA_PASSWORD", &kafkaConfig.Password},
+		{"KAFKA_OAUTH_CLIENT_SECRET", &kafkaConfig.OAuthClientSecret},
+	})
+}
+
+// resolveAvroSecrets resolves the Schema Registry credentials.
+func resolveAvroSecrets(avroConfig *EnvAvroConfig) error {
+	return resolveSecrets(DefaultSecrets, []secretSetting{
+		{"AVRO_SCHEMA_PASSWORD", &avroConfig.Password},
+		{"AVRO_SCHEMA_TOKEN", &avroConfig.Token},
+		{"AVRO_SCHEMA_CA", &avroConfig.AuthCa},
+		{"AVRO_SCHEMA_CERTIFICATE", &avroConfig.AuthCertificate},
+		{"AVRO_SCHEMA_KEY", &avroConfig.AuthKey},
+	})
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretsResolveReferences(t *testing.T) {
+	path := writeConfigFile(t, "kafka_pw", "file-password\n")
+	os.Setenv("GOKAFKA_TEST_PASSWORD", "env-password")
+	defer os.Unsetenv("GOKAFKA_TEST_PASSWORD")
+	secrets := NewSecrets()
+
+	fromFile, err := secrets.Secret("file://" + path)
+	assert.Nil(t, err)
+	fromEnv, err := secrets.Secret("env://GOKAFKA_TEST_PASSWORD")
+	assert.Nil(t, err)
+	plain, err := secrets.Secret("plain://password")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "file-password", fromFile)
+	assert.Equal(t, "env-password", fromEnv)
+	assert.Equal(t, "plain://password", plain)
+}
+
+func TestSecretsFailOnMissingReference(t *testing.T) {
+	secrets := NewSecrets()
+
+	_, err := secrets.Secret("env://GOKAFKA_TEST_MISSING")
+
+	assert.Error(t, err)
+}
+
+func TestHTTPSecretProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer vault-token", r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/kafka/password":
+			fmt.Fprint(w, `{"value":"json-password"}`)
+		case "/kafka/text":
+			fmt.Fprint(w, "text-password\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	secrets := NewSecrets()
+	secrets.Register("vault", NewHTTPSecretProvider(server.URL, "vault-token"))
+
+	fromJson, err := secrets.Secret("vault://kafka/password")
+	assert.Nil(t, err)
+	fromText, err := secrets.Secret("vault://kafka/text")
+	assert.Nil(t, err)
+	_, err = secrets.Secret("vault://kafka/missing")
+
+	assert.Equal(t, "json-password", fromJson)
+	assert.Equal(t, "text-password", fromText)
+	assert.Error(t, err)
+}
+
+func TestGetKafkaResolvesSecrets(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	passwordPath := writeConfigFile(t, "kafka_pw", "KafkaPassword")
+	os.Setenv("KAFKA_BROKERS", "localhost:9092")
+	os.Setenv("KAFKA_GROUP", "group")
+	os.Setenv("KAFKA_TOPICS", "topic")
+	os.Setenv("KAFKA_VERSION", "2.1.1")
+	os.Setenv("KAFKA_AUTHENTICATION_TYPE", "sasl_ssl")
+	os.Setenv("KAFKA_USERNAME", "KafkaUsername")
+	os.Setenv("KAFKA_PASSWORD", "file://"+passwordPath)
+	os.Setenv("KAFKA_AUTHENTICATION_CA", "file:///run/secrets/kafka_ca")
+
+	kafkaConfig, err := GetKafka()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "KafkaPassword", kafkaConfig.Password)
+	assert.Empty(t, kafkaConfig.AuthCa)
+	assert.Equal(t, "/run/secrets/kafka_ca", kafkaConfig.AuthCaFile)
+}
+
+func TestGetAvroFailsOnUnresolvedSecrets(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	os.Setenv("AVRO_SCHEMA_URL", "http://localhost:8081")
+	os.Setenv("AVRO_SCHEMA_PASSWORD", "env://GOKAFKA_TEST_MISSING")
+
+	_, err := GetAvro()
+
+	assert.True(t, errors.Is(err, ErrInvalidSecret))
+	assert.Contains(t, err.Error(), "AVRO_SCHEMA_PASSWORD")
+}
+
+func TestSecretsWatchTellsRotatedReferences(t *testing.T) {
+	path := writeConfigFile(t, "kafka_pw", "first")
+	secrets := NewSecrets()
+	_, err := secrets.Secret("file://" + path)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotated := make(chan string, 1)
+	go secrets.Watch(ctx, 10*time.Millisecond, func(ref string) {
+		rotated <- ref
+	})
+
+	assert.Nil(t, os.WriteFile(path, []byte("second"), 0644))
+
+	select {
+	case ref := <-rotated:
+		assert.Equal(t, "file://"+path, ref)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the rotated secret was not told")
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// CertificateReloader gives the client certificate read from files, reading
+// them again whenever they change, so long running consumers keep working
+// after the certificate is rotated, e.g: by cert-manager.
+// Use its GetClientCertificate on tls.Config.
+type CertificateReloader struct {
+	certificateFile string
+	keyFile         string
+
+	lock        sync.Mutex
+	certificate *tls.Certificate
+	modTimes    [2]time.Time
+}
+
+func NewCertificateReloader(certificateFile string, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{
+		certificateFile: certificateFile,
+		keyFile:         keyFile,
+	}
+
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// GetClientCertificate is called on every TLS handshake, so a new
+// connection always uses the latest certificate on disk.
+func (reloader *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return reloader.reload()
+}
+
+// reload reads the files again when they changed. When the new files
+// can't be loaded, e.g: the key was rotated but not the certificate yet,
+// the previous certificate is kept until both are in place.
+func (reloader *CertificateReloader) reload() (*tls.Certificate, error) {
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	modTimes, err := reloader.stat()
+	if err != nil {
+		return reloader.fallback(err)
+	}
+
+	if reloader.certificate != nil && modTimes == reloader.modTimes {
+		return reloader.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(reloader.certificateFile, reloader.keyFile)
+	if err != nil {
+		return reloader.fallback(err)
+	}
+
+	if reloader.certificate != nil {
+		log.Print("Kafka client certificate reloaded from " + reloader.certificateFile)
+	}
+	reloader.certificate = &certificate
+	reloader.modTimes = modTimes
+
+	return reloader.certificate, nil
+}
+
+func (reloader *CertificateReloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{reloader.certificateFile, reloader.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+func (reloader *CertificateReloader) fallback(err error) (*tls.Certificate, error) {
+	if reloader.certificate == nil {
+		return nil, errors.Wrap(err, "Error loading Kafka client certificate")
+	}
+
+	log.Error().Err(err).Msg("Error reloading Kafka client certificate, keeping the previous one.")
+
+	return reloader.certificate, nil
+}
+
+// readPem gives the PEM content, read from file when it is given.
+func readPem(content string, file string) (string, error) {
+	if len(file) == 0 {
+		return content, nil
+	}
+
+	read, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrap(err, "Error reading PEM file")
+	}
+
+	return string(read), nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCertificateFiles(t *testing.T, dir string, name string, modTime time.Time) {
+	certificate, key := newTestCertificate(t, name)
+	for file, content := range map[string]string{"tls.crt": certificate, "tls.key": key} {
+		path := filepath.Join(dir, file)
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+	}
+}
+
+func certificateName(t *testing.T, reloader *CertificateReloader) string {
+	certificate, err := reloader.GetClientCertificate(nil)
+	assert.Nil(t, err)
+
+	parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+	assert.Nil(t, err)
+
+	return parsed.Subject.CommonName
+}
+
+func TestCertificateReloaderReloadsRotatedFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "certs")
+	defer os.RemoveAll(dir)
+	writeCertificateFiles(t, dir, "first", time.Now().Add(-time.Minute))
+
+	reloader, err := NewCertificateReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	assert.Nil(t, err)
+	assert.Equal(t, "first", certificateName(t, reloader))
+
+	writeCertificateFiles(t, dir, "rotated", time.Now())
+
+	assert.Equal(t, "rotated", certificateName(t, reloader))
+}
+
+func TestCertificateReloaderKeepsCertificateOnBrokenRotation(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "certs")
+	defer os.RemoveAll(dir)
+	writeCertificateFiles(t, dir, "first", time.Now().Add(-time.Minute))
+
+	reloader, _ := NewCertificateReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	ioutil.WriteFile(filepath.Join(dir, "tls.key"), []byte("half written"), 0600)
+
+	assert.Equal(t, "first", certificateName(t, reloader))
+}
+
+func TestCertificateReloaderFailsWithoutFiles(t *testing.T) {
+	_, err := NewCertificateReloader("/does/not/exist.crt", "/does/not/exist.key")
+
+	assert.Error(t, err)
+}
+
+func TestShouldConfigureSSLFromFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "certs")
+	defer os.RemoveAll(dir)
+	writeCertificateFiles(t, dir, "client", time.Now())
+
+	config, err := New(
+		WithBrokers("broker"),
+		WithVersion("2.1.1"),
+		WithGroup("group"),
+		WithTopics("topic"),
+		WithSSLFiles(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "tls.crt")),
+	)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, config.Kafka.Net.TLS.Config.GetClientCertificate)
+	assert.NotNil(t, config.Kafka.Net.TLS.Config.RootCAs)
+}
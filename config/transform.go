@@ -2,94 +2,295 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
 )
 
 // Creating error vars like this
 // We'll be able to use it to check what kind of error without
 // reading the content of error.
-// e.g: if (err == config.AVRO_SCHEMA_URL)
+// e.g: if errors.Is(err, config.ErrInvalidUrl)
 var (
-	invalidUrl = errors.New("no Avro url AvroConfig.Url defined, please set the AVRO_SCHEMA_URL env")
+	ErrInvalidUrl          = errors.New("no Avro url AvroConfig.Url defined, please set the AVRO_SCHEMA_URL or AVRO_SCHEMA_DIR env")
+	ErrInvalidAvroTimeout  = errors.New("invalid AvroConfig.Timeout given, please set the AVRO_SCHEMA_TIMEOUT env as a duration, e.g: 5s")
+	ErrInvalidAvroRetries  = errors.New("invalid AvroConfig.Retries given, please set the AVRO_SCHEMA_RETRIES env as a positive number")
+	ErrInvalidAvroBackoff  = errors.New("invalid AvroConfig.RetryBackoff given, please set the AVRO_SCHEMA_RETRY_BACKOFF env as a duration, e.g: 200ms")
+	ErrInvalidAvroAuthMtls = errors.New("not enough mTLS Auth config defined, please set both AVRO_SCHEMA_CERTIFICATE and AVRO_SCHEMA_KEY envs")
+)
+
+const (
+	defaultAvroTimeout      = 5 * time.Second
+	defaultAvroRetries      = 3
+	defaultAvroRetryBackoff = 200 * time.Millisecond
+)
+
+const (
+	defaultKerberosConfig      = "/etc/krb5.conf"
+	defaultKerberosServiceName = "kafka"
 )
 
 func GetAvro() (config EnvAvroConfig, err error) {
+	return GetAvroWithPrefix("")
+}
+
+// GetAvroWithPrefix is GetAvro reading the envs with the given prefix, e.g: ORDERS_AVRO_SCHEMA_URL.
+func GetAvroWithPrefix(prefix string) (config EnvAvroConfig, err error) {
+	avroConfig, _, err := loadAvro(prefix, os.Getenv(prefix+ConfigFileEnv))
+
+	return avroConfig, prefixed(prefix, err)
+}
+
+// LoadAvro reads the Avro settings from the YAML or JSON file at path, when given,
+// with the env on top of it, telling where each value came from.
+func LoadAvro(path string) (EnvAvroConfig, Sources, error) {
+	return loadAvro("", path)
+}
+
+func loadAvro(prefix string, path string) (EnvAvroConfig, Sources, error) {
 	avroConfig := EnvAvroConfig{
-		Url:      os.Getenv("AVRO_SCHEMA_URL"),
-		Username: os.Getenv("AVRO_SCHEMA_USERNAME"),
-		Password: os.Getenv("AVRO_SCHEMA_PASSWORD"),
+		Timeout:      defaultAvroTimeout,
+		Retries:      defaultAvroRetries,
+		RetryBackoff: defaultAvroRetryBackoff,
+	}
+	sources := Sources{}
+
+	fileLayers, err := layers(prefix, path)
+	if err != nil {
+		return avroConfig, sources, err
+	}
+
+	err = combine(load(avroBindings(&avroConfig), fileLayers, sources), resolveAvroSecrets(&avroConfig))
+
+	return avroConfig, sources, combine(err, validateAvro(avroConfig))
+}
+
+// validateAvro checks every Avro setting, returning all problems found.
+func validateAvro(avroConfig EnvAvroConfig) error {
+	var errs []error
+
+	if len(avroConfig.Url) == 0 && len(avroConfig.Dir) == 0 {
+		errs = append(errs, ErrInvalidUrl)
 	}
 
-	if len(avroConfig.Url) == 0 {
-		return avroConfig, invalidUrl
+	if avroConfig.Timeout <= 0 {
+		errs = append(errs, ErrInvalidAvroTimeout)
 	}
 
-	return avroConfig, nil
+	if avroConfig.Retries < 0 {
+		errs = append(errs, ErrInvalidAvroRetries)
+	}
+
+	if avroConfig.RetryBackoff < 0 {
+		errs = append(errs, ErrInvalidAvroBackoff)
+	}
+
+	if (len(avroConfig.AuthCertificate) == 0) != (len(avroConfig.AuthKey) == 0) {
+		errs = append(errs, ErrInvalidAvroAuthMtls)
+	}
+
+	return combine(errs...)
 }
 
 // Creating error vars like this
 // We'll be able to use it to check what kind of error without
 // reading the content of error.
-// e.g: if (err == config.invalidBroker)
+// e.g: if errors.Is(err, config.ErrInvalidBroker)
 var (
-	invalidBroker        = errors.New("no Kafka bootstrap kafkaConfig.Brokers defined, please set the KAFKA_BROKERS env")
-	invalidTopic         = errors.New("no kafkaConfig.Topic given to be consumed, please set the KAFKA_TOPICS env")
-	invalidConsumerGroup = errors.New("no Kafka consumer kafkaConfig.Group defined, please set the KAFKA_GROUP env")
-	invalidAuthSsl       = errors.New("not enough SSL Auth config defined, please set KAFKA_AUTHENTICATION_CA, KAFKA_AUTHENTICATION_CERTIFICATE, KAFKA_AUTHENTICATION_KEY envs")
-	invalidAuthSaslSsl   = errors.New("not enough sasl_ssl Auth config defined, please set KAFKA_USERNAME, KAFKA_PASSWORD envs")
+	ErrInvalidBroker              = errors.New("no Kafka bootstrap kafkaConfig.Brokers defined, please set the KAFKA_BROKERS env")
+	ErrInvalidTopic               = errors.New("no kafkaConfig.Topic given to be consumed, please set the KAFKA_TOPICS env")
+	ErrInvalidConsumerGroup       = errors.New("no Kafka consumer kafkaConfig.Group defined, please set the KAFKA_GROUP env")
+	ErrInvalidAuthSsl             = errors.New("not enough SSL Auth config defined, please set KAFKA_AUTHENTICATION_CA, KAFKA_AUTHENTICATION_CERTIFICATE, KAFKA_AUTHENTICATION_KEY envs, or their _FILE variants, the certificate and key go together")
+	ErrInvalidAuthSaslSsl         = errors.New("not enough sasl_ssl/sasl_plaintext Auth config defined, please set KAFKA_USERNAME, KAFKA_PASSWORD envs")
+	ErrInvalidVersion             = errors.New("invalid kafkaConfig.Version given, please set the KAFKA_VERSION env as a Kafka version, e.g: 2.1.1")
+	ErrInvalidAssignor            = errors.New("unrecognized kafkaConfig.Assignor given, please set the KAFKA_ASSIGNOR env as range, roundrobin or sticky")
+	ErrInvalidAuthType            = errors.New("unrecognized kafkaConfig.AuthType given, please set the KAFKA_AUTHENTICATION_TYPE env as none, ssl, sasl_ssl or sasl_plaintext")
+	ErrInvalidSaslMechanism       = errors.New("unrecognized kafkaConfig.SaslMechanism given, please set the KAFKA_SASL_MECHANISM env as PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, OAUTHBEARER or GSSAPI")
+	ErrInvalidTlsMinVersion       = errors.New("unrecognized kafkaConfig.TlsMinVersion given, please set the KAFKA_TLS_MIN_VERSION env as 1.0, 1.1, 1.2 or 1.3")
+	ErrInvalidSecurityProtocol    = errors.New("unrecognized kafkaConfig.SecurityProtocol given, please set the KAFKA_SECURITY_PROTOCOL env as PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL")
+	ErrInvalidAuthOAuth           = errors.New("not enough OAUTHBEARER Auth config defined, please set KAFKA_OAUTH_TOKEN_URL, KAFKA_OAUTH_CLIENT_ID, KAFKA_OAUTH_CLIENT_SECRET envs")
+	ErrInvalidAuthKerberos        = errors.New("not enough GSSAPI Auth config defined, please set KAFKA_KERBEROS_PRINCIPAL, KAFKA_KERBEROS_REALM envs and KAFKA_KERBEROS_KEYTAB or KAFKA_PASSWORD")
+	ErrInvalidKerberosDisableFast = errors.New("invalid kafkaConfig.KerberosDisableFast given, please set the KAFKA_KERBEROS_DISABLE_FAST env as true or false")
+	ErrInvalidSecret              = errors.New("unresolved secret reference given, please check the file://, env:// or registered provider reference")
+	ErrInvalidOffsetReset         = errors.New("unrecognized kafkaConfig.OffsetReset given, please set the KAFKA_OFFSET_RESET env as earliest or latest")
+	ErrInvalidStartAt             = errors.New("invalid kafkaConfig.StartAt given, please set the KAFKA_OFFSET_START_AT env as a RFC3339 timestamp or a duration ago, e.g: 2h")
+	ErrUnsupportedAssignor        = errors.New("the cooperative-sticky assignor needs incremental cooperative rebalancing, which the sarama version in use doesn't support, please set the KAFKA_ASSIGNOR env as range, roundrobin or sticky")
 )
 
+var authTypes = map[string]bool{
+	"":               true,
+	"none":           true,
+	"ssl":            true,
+	"sasl_ssl":       true,
+	"sasl_plaintext": true,
+}
+
+// securityProtocols are the Kafka security.protocol values, accepted on KAFKA_SECURITY_PROTOCOL.
+var securityProtocols = map[string]bool{
+	"PLAINTEXT":      true,
+	"SSL":            true,
+	"SASL_PLAINTEXT": true,
+	"SASL_SSL":       true,
+}
+
+// securityProtocol gives the Kafka security.protocol in use, from
+// KAFKA_SECURITY_PROTOCOL or else from the KAFKA_AUTHENTICATION_TYPE.
+func securityProtocol(kafkaConfig EnvKafkaConfig) string {
+	if len(kafkaConfig.SecurityProtocol) > 0 {
+		return strings.ToUpper(kafkaConfig.SecurityProtocol)
+	}
+
+	switch kafkaConfig.AuthType {
+	case "ssl":
+		return "SSL"
+	case "sasl_ssl":
+		return "SASL_SSL"
+	case "sasl_plaintext":
+		return "SASL_PLAINTEXT"
+	}
+
+	return "PLAINTEXT"
+}
+
+var saslMechanisms = map[string]bool{
+	"":              true,
+	"PLAIN":         true,
+	"SCRAM-SHA-256": true,
+	"SCRAM-SHA-512": true,
+	"OAUTHBEARER":   true,
+	"GSSAPI":        true,
+}
+
 func GetKafka() (config EnvKafkaConfig, err error) {
+	return GetKafkaWithPrefix("")
+}
+
+// GetKafkaWithPrefix is GetKafka reading the envs with the given prefix, e.g: ORDERS_KAFKA_BROKERS.
+// It fails with the same error vars, which can be checked with errors.Is.
+func GetKafkaWithPrefix(prefix string) (config EnvKafkaConfig, err error) {
+	kafkaConfig, _, err := loadKafka(prefix, os.Getenv(prefix+ConfigFileEnv))
+
+	return kafkaConfig, prefixed(prefix, err)
+}
+
+// LoadKafka reads the Kafka settings from the YAML or JSON file at path, when given,
+// with the env on top of it, telling where each value came from.
+func LoadKafka(path string) (EnvKafkaConfig, Sources, error) {
+	return loadKafka("", path)
+}
+
+func loadKafka(prefix string, path string) (EnvKafkaConfig, Sources, error) {
 	kafkaConfig := EnvKafkaConfig{
-		Brokers:         os.Getenv("KAFKA_BROKERS"),
-		Version:         os.Getenv("KAFKA_VERSION"),
-		ConsumerGroup:   os.Getenv("KAFKA_GROUP"),
-		Topic:           os.Getenv("KAFKA_TOPICS"),
-		Assignor:        os.Getenv("KAFKA_ASSIGNOR"),
-		OldestFirst:     true,
-		AuthType:        os.Getenv("KAFKA_AUTHENTICATION_TYPE"),
-		AuthCa:          os.Getenv("KAFKA_AUTHENTICATION_CA"),
-		AuthKey:         os.Getenv("KAFKA_AUTHENTICATION_KEY"),
-		AuthCertificate: os.Getenv("KAFKA_AUTHENTICATION_CERTIFICATE"),
-		Username:        os.Getenv("KAFKA_USERNAME"),
-		Password:        os.Getenv("KAFKA_PASSWORD"),
+		OldestFirst: true,
 	}
+	sources := Sources{}
+
+	fileLayers, err := layers(prefix, path)
+	if err != nil {
+		return kafkaConfig, sources, err
+	}
+
+	err = combine(load(kafkaBindings(&kafkaConfig), fileLayers, sources), resolveKafkaSecrets(&kafkaConfig))
+
+	return kafkaConfig, sources, combine(err, validateKafka(kafkaConfig))
+}
+
+// prefixed tells which env prefix an error came from, keeping the error
+// vars behind it for errors.Is.
+func prefixed(prefix string, err error) error {
+	if err == nil || len(prefix) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("on %s prefixed envs: %w", prefix, err)
+}
+
+// validateKafka checks every Kafka setting, returning all problems found.
+func validateKafka(kafkaConfig EnvKafkaConfig) error {
+	var errs []error
 
 	if len(kafkaConfig.Brokers) == 0 {
-		return kafkaConfig, invalidBroker
+		errs = append(errs, ErrInvalidBroker)
 	}
 
 	if len(kafkaConfig.Topic) == 0 {
-		return kafkaConfig, invalidTopic
+		errs = append(errs, ErrInvalidTopic)
 	}
 
 	if len(kafkaConfig.ConsumerGroup) == 0 {
-		return kafkaConfig, invalidConsumerGroup
+		errs = append(errs, ErrInvalidConsumerGroup)
 	}
 
-	if kafkaConfig.AuthType == "ssl" {
-		if len(kafkaConfig.AuthKey) == 0 {
-			return kafkaConfig, invalidAuthSsl
-		}
+	if _, err := sarama.ParseKafkaVersion(kafkaConfig.Version); err != nil {
+		errs = append(errs, fmt.Errorf("%w, got %q", ErrInvalidVersion, kafkaConfig.Version))
+	}
 
-		if len(kafkaConfig.AuthCa) == 0 {
-			return kafkaConfig, invalidAuthSsl
-		}
+	if _, err := assignor(kafkaConfig.Assignor); err != nil {
+		errs = append(errs, err)
+	}
+
+	if !authTypes[kafkaConfig.AuthType] {
+		errs = append(errs, fmt.Errorf("%w, got %q", ErrInvalidAuthType, kafkaConfig.AuthType))
+	}
+
+	protocol := securityProtocol(kafkaConfig)
+	if len(kafkaConfig.SecurityProtocol) > 0 && !securityProtocols[protocol] {
+		errs = append(errs, fmt.Errorf("%w, got %q", ErrInvalidSecurityProtocol, kafkaConfig.SecurityProtocol))
+	}
 
-		if len(kafkaConfig.AuthCertificate) == 0 {
-			return kafkaConfig, invalidAuthSsl
+	hasKey := len(kafkaConfig.AuthKey) > 0 || len(kafkaConfig.AuthKeyFile) > 0
+	hasCa := len(kafkaConfig.AuthCa) > 0 || len(kafkaConfig.AuthCaFile) > 0
+	hasCertificate := len(kafkaConfig.AuthCertificate) > 0 || len(kafkaConfig.AuthCertificateFile) > 0
+	// The certificate and key are rotated together, both must come from the same place.
+	fromFiles := (len(kafkaConfig.AuthCertificateFile) > 0) == (len(kafkaConfig.AuthKeyFile) > 0)
+
+	// The legacy ssl auth type always authenticates with a client certificate,
+	// while the SSL protocol may only verify the server.
+	if len(kafkaConfig.SecurityProtocol) == 0 && kafkaConfig.AuthType == "ssl" {
+		if !hasKey || !hasCa || !hasCertificate || !fromFiles {
+			errs = append(errs, ErrInvalidAuthSsl)
+		}
+	} else if protocol == "SSL" || protocol == "SASL_SSL" {
+		if hasKey != hasCertificate || !fromFiles {
+			errs = append(errs, ErrInvalidAuthSsl)
 		}
 	}
 
-	if kafkaConfig.AuthType == "sasl_ssl" {
-		if len(kafkaConfig.Username) == 0 {
-			return kafkaConfig, invalidAuthSaslSsl
+	if protocol == "SASL_SSL" || protocol == "SASL_PLAINTEXT" {
+		switch strings.ToUpper(kafkaConfig.SaslMechanism) {
+		case "OAUTHBEARER":
+			if kafkaConfig.TokenProvider == nil && (len(kafkaConfig.OAuthTokenUrl) == 0 || len(kafkaConfig.OAuthClientId) == 0 || len(kafkaConfig.OAuthClientSecret) == 0) {
+				errs = append(errs, ErrInvalidAuthOAuth)
+			}
+		case "GSSAPI":
+			if len(kafkaConfig.KerberosPrincipal) == 0 || len(kafkaConfig.KerberosRealm) == 0 || (len(kafkaConfig.KerberosKeytab) == 0 && len(kafkaConfig.Password) == 0) {
+				errs = append(errs, ErrInvalidAuthKerberos)
+			}
+		default:
+			if len(kafkaConfig.Username) == 0 || len(kafkaConfig.Password) == 0 {
+				errs = append(errs, ErrInvalidAuthSaslSsl)
+			}
 		}
 
-		if len(kafkaConfig.Password) == 0 {
-			return kafkaConfig, invalidAuthSaslSsl
+		if !saslMechanisms[strings.ToUpper(kafkaConfig.SaslMechanism)] {
+			errs = append(errs, fmt.Errorf("%w, got %q", ErrInvalidSaslMechanism, kafkaConfig.SaslMechanism))
 		}
 	}
 
-	return kafkaConfig, nil
+	if offsetReset := strings.ToLower(kafkaConfig.OffsetReset); offsetReset != "" && offsetReset != "earliest" && offsetReset != "latest" {
+		errs = append(errs, fmt.Errorf("%w, got %q", ErrInvalidOffsetReset, kafkaConfig.OffsetReset))
+	}
+
+	if _, err := startTime(kafkaConfig.StartAt, time.Now()); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, ok := tlsVersions[kafkaConfig.TlsMinVersion]; len(kafkaConfig.TlsMinVersion) > 0 && !ok {
+		errs = append(errs, fmt.Errorf("%w, got %q", ErrInvalidTlsMinVersion, kafkaConfig.TlsMinVersion))
+	}
+
+	return combine(errs...)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -94,3 +95,63 @@ func TestGetKafkaConfigWithInvalidSSL(t *testing.T) {
 	assert.Equal(t, "test_broker", kafkaConfig.Brokers)
 	assert.Equal(t, "ssl", kafkaConfig.AuthType)
 }
+
+func TestGetAvroConfigWithRegistrySettings(t *testing.T) {
+	os.Setenv("AVRO_SCHEMA_URL", "http://schema-registry")
+	os.Setenv("AVRO_SCHEMA_TOKEN", "test_token")
+	os.Setenv("AVRO_SCHEMA_TIMEOUT", "10s")
+	os.Setenv("AVRO_SCHEMA_RETRIES", "5")
+	os.Setenv("AVRO_SCHEMA_RETRY_BACKOFF", "1s")
+	os.Setenv("AVRO_SCHEMA_CACHE_DIR", "/tmp/schemas")
+	defer os.Unsetenv("AVRO_SCHEMA_TOKEN")
+	defer os.Unsetenv("AVRO_SCHEMA_TIMEOUT")
+	defer os.Unsetenv("AVRO_SCHEMA_RETRIES")
+	defer os.Unsetenv("AVRO_SCHEMA_RETRY_BACKOFF")
+	defer os.Unsetenv("AVRO_SCHEMA_CACHE_DIR")
+
+	avroConfig, err := GetAvro()
+
+	assert.Empty(t, err)
+	assert.Equal(t, "test_token", avroConfig.Token)
+	assert.Equal(t, 10*time.Second, avroConfig.Timeout)
+	assert.Equal(t, 5, avroConfig.Retries)
+	assert.Equal(t, time.Second, avroConfig.RetryBackoff)
+	assert.Equal(t, "/tmp/schemas", avroConfig.CacheDir)
+}
+
+func TestGetAvroErrorOnInvalidTimeout(t *testing.T) {
+	os.Setenv("AVRO_SCHEMA_URL", "http://schema-registry")
+	os.Setenv("AVRO_SCHEMA_TIMEOUT", "ten seconds")
+	defer os.Unsetenv("AVRO_SCHEMA_TIMEOUT")
+
+	_, err := GetAvro()
+	assert.Equal(t, ErrInvalidAvroTimeout, err)
+}
+
+func TestGetKafkaConfigWithPrefix(t *testing.T) {
+	unsetEnvs()
+	t.Setenv("KAFKA_BROKERS", "default_broker")
+	t.Setenv("ORDERS_KAFKA_BROKERS", "orders_broker")
+	t.Setenv("ORDERS_KAFKA_GROUP", "orders_group")
+	t.Setenv("ORDERS_KAFKA_TOPICS", "orders")
+	t.Setenv("ORDERS_KAFKA_VERSION", "2.1.1")
+
+	kafkaConfig, err := GetKafkaWithPrefix("ORDERS_")
+
+	assert.Empty(t, err)
+	assert.Equal(t, "orders_broker", kafkaConfig.Brokers)
+	assert.Equal(t, "orders_group", kafkaConfig.ConsumerGroup)
+	assert.Equal(t, "orders", kafkaConfig.Topic)
+}
+
+func TestGetKafkaConfigWithPrefixKeepsErrorVars(t *testing.T) {
+	unsetEnvs()
+	t.Setenv("KAFKA_GROUP", "default_group")
+	t.Setenv("PAYMENTS_KAFKA_BROKERS", "payments_broker")
+	t.Setenv("PAYMENTS_KAFKA_TOPICS", "payments")
+
+	_, err := GetKafkaWithPrefix("PAYMENTS_")
+
+	assert.ErrorIs(t, err, ErrInvalidConsumerGroup)
+	assert.Contains(t, err.Error(), "PAYMENTS_")
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// ValidationErrors lists every problem found on a config at once, so a
+// misconfigured deploy doesn't need one attempt per missing setting.
+// Each of the error vars behind it can be checked with errors.Is.
+type ValidationErrors []error
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return "invalid config:\n- " + strings.Join(messages, "\n- ")
+}
+
+func (errs ValidationErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// combine joins the given errors into ValidationErrors, skipping nils.
+// A single error is returned as is, so it can still be compared with ==.
+func combine(errs ...error) error {
+	var combined ValidationErrors
+	for _, err := range errs {
+		var validationErrors ValidationErrors
+		if errors.As(err, &validationErrors) {
+			combined = append(combined, validationErrors...)
+		} else if err != nil {
+			combined = append(combined, err)
+		}
+	}
+
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	}
+
+	return combined
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKafkaListsEveryProblem(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	os.Setenv("KAFKA_VERSION", "two")
+	os.Setenv("KAFKA_ASSIGNOR", "ranged")
+	os.Setenv("KAFKA_AUTHENTICATION_TYPE", "sasl_ssl")
+
+	_, err := GetKafka()
+
+	var validationErrors ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+	assert.Len(t, validationErrors, 6)
+	assert.ErrorIs(t, err, ErrInvalidBroker)
+	assert.ErrorIs(t, err, ErrInvalidTopic)
+	assert.ErrorIs(t, err, ErrInvalidConsumerGroup)
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+	assert.ErrorIs(t, err, ErrInvalidAssignor)
+	assert.ErrorIs(t, err, ErrInvalidAuthSaslSsl)
+	assert.Contains(t, err.Error(), `got "ranged"`)
+}
+
+func TestGetKafkaListsMissingVersionWithOtherProblems(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	os.Setenv("KAFKA_GROUP", "test_unit")
+	os.Setenv("KAFKA_TOPICS", "test_topic")
+
+	_, err := GetKafka()
+
+	var validationErrors ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+	assert.Len(t, validationErrors, 2)
+	assert.ErrorIs(t, err, ErrInvalidBroker)
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+}
+
+func TestGetKafkaRefusesUnknownAuthType(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	os.Setenv("KAFKA_BROKERS", "test_broker")
+	os.Setenv("KAFKA_GROUP", "test_unit")
+	os.Setenv("KAFKA_TOPICS", "test_topic")
+	os.Setenv("KAFKA_AUTHENTICATION_TYPE", "kerberos")
+
+	_, err := GetKafka()
+
+	assert.ErrorIs(t, err, ErrInvalidAuthType)
+}
+
+func TestGetAvroListsParseAndValidationProblems(t *testing.T) {
+	unsetEnvs()
+	defer unsetEnvs()
+	os.Setenv("AVRO_SCHEMA_RETRIES", "many")
+	os.Setenv("AVRO_SCHEMA_KEY", "key")
+
+	_, err := GetAvro()
+
+	assert.ErrorIs(t, err, ErrInvalidUrl)
+	assert.ErrorIs(t, err, ErrInvalidAvroRetries)
+	assert.ErrorIs(t, err, ErrInvalidAvroAuthMtls)
+}
@@ -0,0 +1,231 @@
+package consumer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Acknowledgement is handed with each message to an AckAction, which calls Ack
+// once the message is done, or Nack when it failed, even later on another goroutine.
+type Acknowledgement struct {
+	offset  int64
+	tracker *ackTracker
+	once    sync.Once
+}
+
+// Ack marks the message as done. Its offset is committed once every message
+// before it on the partition has been acknowledged too.
+func (ack *Acknowledgement) Ack() {
+	ack.once.Do(func() {
+		ack.tracker.ack(ack.offset)
+	})
+}
+
+// Nack stops consuming the partition with the given error, so the message and
+// the ones not acknowledged after it are delivered again on the next session.
+func (ack *Acknowledgement) Nack(err error) {
+	ack.once.Do(func() {
+		ack.tracker.nack(err)
+	})
+}
+
+// AckConsumer hands each message to the Action with an Acknowledgement, committing
+// the acknowledged offsets synchronously every CommitInterval or CommitEvery acks,
+// whichever comes first, instead of relying on sarama's auto commit.
+type AckConsumer struct {
+	Ready          chan bool
+	Action         AckAction
+	CommitInterval time.Duration
+	CommitEvery    int
+
+	lock     sync.Mutex
+	trackers map[string]*ackTracker
+}
+
+func (consumer *AckConsumer) IsReady() chan bool {
+	return consumer.Ready
+}
+
+func (consumer *AckConsumer) SetReady(ready chan bool) {
+	consumer.Ready = ready
+}
+
+// Configure turns sarama's auto commit off, the offsets being committed by the consumer.
+func (consumer *AckConsumer) Configure(saramaConfig *sarama.Config) {
+	saramaConfig.Consumer.Offsets.AutoCommit.Enable = false
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (consumer *AckConsumer) Setup(sarama.ConsumerGroupSession) error {
+	// Mark the consumer as ready
+	markReady(consumer.Ready)
+
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited,
+// committing the progress acknowledged since the last commit.
+func (consumer *AckConsumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	session.Commit()
+
+	consumer.lock.Lock()
+	consumer.trackers = nil
+	consumer.lock.Unlock()
+
+	return nil
+}
+
+// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
+func (consumer *AckConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	tracker := newAckTracker(session, claim.Topic(), claim.Partition(), consumer.CommitEvery)
+	consumer.track(tracker)
+
+	var interval <-chan time.Time
+	if consumer.CommitInterval > 0 {
+		ticker := time.NewTicker(consumer.CommitInterval)
+		defer ticker.Stop()
+		interval = ticker.C
+	}
+
+	// NOTE:
+	// Do not move the code below to a goroutine.
+	// The `ConsumeClaim` itself is called within a goroutine, see:
+	// https://github.com/Shopify/sarama/blob/master/consumer_group.go#L27-L29
+	messages := claim.Messages()
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			if err := consumer.Action(message, tracker.track(message.Offset)); err != nil {
+				return err
+			}
+		case err := <-tracker.nacked:
+			return err
+		case <-tracker.full:
+			session.Commit()
+		case <-interval:
+			session.Commit()
+		}
+	}
+}
+
+// Unacknowledged gives the offsets of the partition handed to the Action and not acknowledged
+// yet, until the session is cleaned up. A Nack gives up on them, as they are delivered again.
+func (consumer *AckConsumer) Unacknowledged(topic string, partition int32) []int64 {
+	consumer.lock.Lock()
+	tracker := consumer.trackers[fmt.Sprintf("%s/%d", topic, partition)]
+	consumer.lock.Unlock()
+
+	if tracker == nil {
+		return nil
+	}
+
+	return tracker.unacknowledged()
+}
+
+// track keeps the tracker of the claim, for Unacknowledged, until the session is cleaned up.
+func (consumer *AckConsumer) track(tracker *ackTracker) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	if consumer.trackers == nil {
+		consumer.trackers = map[string]*ackTracker{}
+	}
+	consumer.trackers[fmt.Sprintf("%s/%d", tracker.topic, tracker.partition)] = tracker
+}
+
+// ackTracker marks the offsets of a partition in order, as their messages are acknowledged.
+type ackTracker struct {
+	session     sarama.ConsumerGroupSession
+	topic       string
+	partition   int32
+	commitEvery int
+
+	lock        sync.Mutex
+	pending     []int64
+	acked       map[int64]bool
+	uncommitted int
+	failed      bool
+	nacked      chan error
+	full        chan struct{}
+}
+
+func newAckTracker(session sarama.ConsumerGroupSession, topic string, partition int32, commitEvery int) *ackTracker {
+	return &ackTracker{
+		session:     session,
+		topic:       topic,
+		partition:   partition,
+		commitEvery: commitEvery,
+		acked:       map[int64]bool{},
+		nacked:      make(chan error, 1),
+		full:        make(chan struct{}, 1),
+	}
+}
+
+func (tracker *ackTracker) track(offset int64) *Acknowledgement {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.pending = append(tracker.pending, offset)
+
+	return &Acknowledgement{offset: offset, tracker: tracker}
+}
+
+func (tracker *ackTracker) ack(offset int64) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.acked[offset] = true
+	for len(tracker.pending) > 0 && tracker.acked[tracker.pending[0]] {
+		done := tracker.pending[0]
+		tracker.pending = tracker.pending[1:]
+		delete(tracker.acked, done)
+
+		tracker.session.MarkOffset(tracker.topic, tracker.partition, done+1, "")
+		tracker.uncommitted++
+	}
+
+	if tracker.commitEvery > 0 && tracker.uncommitted >= tracker.commitEvery {
+		tracker.uncommitted = 0
+		select {
+		case tracker.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// unacknowledged gives the pending offsets not acknowledged yet, none once nacked.
+func (tracker *ackTracker) unacknowledged() []int64 {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if tracker.failed {
+		return nil
+	}
+
+	var offsets []int64
+	for _, offset := range tracker.pending {
+		if !tracker.acked[offset] {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	return offsets
+}
+
+func (tracker *ackTracker) nack(err error) {
+	tracker.lock.Lock()
+	tracker.failed = true
+	tracker.lock.Unlock()
+
+	select {
+	case tracker.nacked <- err:
+	default:
+	}
+}
@@ -0,0 +1,183 @@
+package consumer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldCommitAcknowledgedOffsetsInOrder(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+
+	var first *Acknowledgement
+	action := func(message *sarama.ConsumerMessage, ack *Acknowledgement) error {
+		if message.Offset == 10 {
+			first = ack
+			return nil
+		}
+
+		ack.Ack()
+		first.Ack()
+		return nil
+	}
+
+	consumer := AckConsumer{
+		Ready:       make(chan bool),
+		Action:      action,
+		CommitEvery: 2,
+	}
+
+	messages := make(chan *sarama.ConsumerMessage)
+	go addMessages(messages, &sarama.ConsumerMessage{Offset: 10}, &sarama.ConsumerMessage{Offset: 11})
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders")
+	claim.EXPECT().Partition().Return(int32(0))
+	claim.EXPECT().Messages().Return(messages)
+
+	gomock.InOrder(
+		session.EXPECT().MarkOffset("orders", int32(0), int64(11), ""),
+		session.EXPECT().MarkOffset("orders", int32(0), int64(12), ""),
+		session.EXPECT().Commit().Do(func() { close(messages) }),
+	)
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+
+	// Assertions
+	assert.Nil(t, err)
+}
+
+func TestShouldStopOnNack(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+
+	action := func(message *sarama.ConsumerMessage, ack *Acknowledgement) error {
+		go ack.Nack(errors.New("write not confirmed"))
+		return nil
+	}
+
+	consumer := AckConsumer{
+		Ready:  make(chan bool),
+		Action: action,
+	}
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Offset: 10}
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders")
+	claim.EXPECT().Partition().Return(int32(0))
+	claim.EXPECT().Messages().Return(messages)
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+
+	// Assertions
+	assert.Equal(t, errors.New("write not confirmed"), err)
+}
+
+func TestShouldTurnAutoCommitOffAndCommitOnCleanup(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	session := mocks.NewMockConsumerGroupSession(controller)
+	saramaConfig := sarama.NewConfig()
+	consumer := AckConsumer{}
+
+	// Expectations
+	session.EXPECT().Commit()
+
+	// Actions
+	consumer.Configure(saramaConfig)
+	err := consumer.Cleanup(session)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.False(t, saramaConfig.Consumer.Offsets.AutoCommit.Enable)
+}
+
+func TestShouldTellTheUnacknowledgedOffsetsUntilCleanup(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+
+	var acks []*Acknowledgement
+	consumer := AckConsumer{
+		Ready: make(chan bool),
+		Action: func(message *sarama.ConsumerMessage, ack *Acknowledgement) error {
+			acks = append(acks, ack)
+			return nil
+		},
+	}
+
+	messages := make(chan *sarama.ConsumerMessage, 2)
+	messages <- &sarama.ConsumerMessage{Offset: 10}
+	messages <- &sarama.ConsumerMessage{Offset: 11}
+	close(messages)
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders")
+	claim.EXPECT().Partition().Return(int32(0))
+	claim.EXPECT().Messages().Return(messages)
+	session.EXPECT().MarkOffset("orders", int32(0), int64(11), "")
+	session.EXPECT().MarkOffset("orders", int32(0), int64(12), "")
+	session.EXPECT().Commit()
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+	returned := consumer.Unacknowledged("orders", 0)
+	acks[1].Ack()
+	outOfOrder := consumer.Unacknowledged("orders", 0)
+	acks[0].Ack()
+	acknowledged := consumer.Unacknowledged("orders", 0)
+	consumer.Cleanup(session)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{10, 11}, returned)
+	assert.Equal(t, []int64{10}, outOfOrder)
+	assert.Empty(t, acknowledged)
+	assert.Empty(t, consumer.Unacknowledged("orders", 0))
+}
+
+func TestShouldGiveUpTheUnacknowledgedOffsetsOnNack(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+
+	var ack *Acknowledgement
+	consumer := AckConsumer{
+		Ready: make(chan bool),
+		Action: func(message *sarama.ConsumerMessage, acknowledgement *Acknowledgement) error {
+			ack = acknowledgement
+			return nil
+		},
+	}
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Offset: 10}
+	close(messages)
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders")
+	claim.EXPECT().Partition().Return(int32(0))
+	claim.EXPECT().Messages().Return(messages)
+
+	// Actions
+	consumer.ConsumeClaim(session, claim)
+	ack.Nack(errors.New("write not confirmed"))
+
+	// Assertions
+	assert.Empty(t, consumer.Unacknowledged("orders", 0))
+}
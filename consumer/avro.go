@@ -2,29 +2,33 @@ package consumer
 
 import (
 	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/registry"
 	"github.com/linkedin/goavro"
-	"github.com/riferrei/srclient"
 )
 
 func Codec() (goavro.Codec, error) {
-	kafkaConfig, err := config.GetKafka()
+	return CodecWithPrefix("")
+}
+
+// CodecWithPrefix is Codec reading the envs with the given prefix,
+// e.g: ORDERS_KAFKA_TOPICS and ORDERS_AVRO_SCHEMA_URL.
+func CodecWithPrefix(prefix string) (goavro.Codec, error) {
+	kafkaConfig, err := config.GetKafkaWithPrefix(prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	avroConfig, err := config.GetAvro()
+	schemaRegistryClient, err := registry.SharedWithPrefix(prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	schemaRegistryClient := srclient.CreateSchemaRegistryClient(avroConfig.Url)
-	schemaRegistryClient.SetCredentials(avroConfig.Username, avroConfig.Password)
 	avroSchema, err := schemaRegistryClient.GetLatestSchema(kafkaConfig.Topic + "-value")
 	if err != nil {
 		return nil, err
 	}
 
-	codec, err := goavro.NewCodec(avroSchema.Schema())
+	codec, err := goavro.NewCodec(avroSchema.Schema)
 	if err != nil {
 		return nil, err
 	}
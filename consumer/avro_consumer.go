@@ -2,16 +2,26 @@ package consumer
 
 import (
 	"bytes"
+	"encoding/binary"
 	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/registry"
 	"github.com/linkedin/goavro"
 	"github.com/pkg/errors"
 	"reflect"
+	"sync"
 )
 
 type AvroConsumer struct {
 	Ready  chan bool
 	Action AvroAction
 	Codec  goavro.Codec
+	// Schemas, when given, is used to decode each message with the schema
+	// of the ID it was framed with, instead of the fixed Codec.
+	Schemas registry.Provider
+	// Dedup, when given, skips the messages already processed.
+	Dedup *Dedup
+
+	codecs sync.Map
 }
 
 func (consumer *AvroConsumer) IsReady() chan bool {
@@ -29,14 +39,28 @@ func (consumer *AvroConsumer) getAction() AvroAction {
 // Setup is run at the beginning of a new session, before ConsumeClaim
 func (consumer *AvroConsumer) Setup(sarama.ConsumerGroupSession) error {
 	// Mark the consumer as ready
-	close(consumer.Ready)
+	markReady(consumer.Ready)
 
 	return nil
 }
 
 func (consumer *AvroConsumer) AvroDecode(message *sarama.ConsumerMessage) (*goavro.Record, error) {
+	// Framed messages start with a magic byte and the 4 bytes schema ID.
+	if len(message.Value) < 5 || message.Value[0] != 0 {
+		return nil, errors.New("Message is not a framed Avro message.")
+	}
+
+	codec, err := consumer.codecFor(int(binary.BigEndian.Uint32(message.Value[1:5])))
+	if err != nil {
+		return nil, err
+	}
+
 	bb := bytes.NewBuffer(message.Value[5:])
-	decoded, _ := consumer.Codec.Decode(bb)
+	decoded, err := codec.Decode(bb)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error decoding Avro message")
+	}
+
 	record, ok := decoded.(*goavro.Record)
 	if !ok {
 		return nil, errors.New("Type: " + reflect.TypeOf(decoded).String() + "is not a valid Record.")
@@ -45,6 +69,29 @@ func (consumer *AvroConsumer) AvroDecode(message *sarama.ConsumerMessage) (*goav
 	return record, nil
 }
 
+func (consumer *AvroConsumer) codecFor(id int) (goavro.Codec, error) {
+	if consumer.Schemas == nil {
+		return consumer.Codec, nil
+	}
+
+	if codec, ok := consumer.codecs.Load(id); ok {
+		return codec.(goavro.Codec), nil
+	}
+
+	schema, err := consumer.Schemas.GetSchemaByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodec(schema.Schema)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error parsing schema id %d", id)
+	}
+	consumer.codecs.Store(id, codec)
+
+	return codec, nil
+}
+
 // Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
 func (consumer *AvroConsumer) Cleanup(sarama.ConsumerGroupSession) error {
 	return nil
@@ -57,12 +104,14 @@ func (consumer *AvroConsumer) ConsumeClaim(session sarama.ConsumerGroupSession,
 	// The `ConsumeClaim` itself is called within a goroutine, see:
 	// https://github.com/Shopify/sarama/blob/master/consumer_group.go#L27-L29
 	for message := range claim.Messages() {
-		record, err := consumer.AvroDecode(message)
-		if err != nil {
-			return err
-		}
+		err := consumer.Dedup.process(message, func() error {
+			record, err := consumer.AvroDecode(message)
+			if err != nil {
+				return err
+			}
 
-		err = consumer.Action(record)
+			return consumer.Action(record)
+		})
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,52 @@
+package consumer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/registry"
+	"github.com/linkedin/goavro"
+	"github.com/stretchr/testify/assert"
+)
+
+const userSchema = `{"type":"record","name":"User","fields":[{"name":"id","type":"string"}]}`
+
+func TestShouldDecodeFramedMessageWithLocalSchemas(t *testing.T) {
+	// Set
+	dir, _ := ioutil.TempDir("", "schemas")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "users-value"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "users-value", "1-42.avsc"), []byte(userSchema), 0644)
+
+	schemas, err := registry.NewDirectory(dir)
+	assert.Nil(t, err)
+
+	codec, _ := goavro.NewCodec(userSchema)
+	record, _ := goavro.NewRecord(goavro.RecordSchema(userSchema))
+	record.Set("id", "some-user")
+
+	value := bytes.NewBuffer([]byte{0, 0, 0, 0, 42})
+	assert.Nil(t, codec.Encode(value, record))
+
+	consumer := AvroConsumer{Schemas: schemas}
+
+	// Actions
+	decoded, err := consumer.AvroDecode(&sarama.ConsumerMessage{Value: value.Bytes()})
+
+	// Assertions
+	assert.Nil(t, err)
+	id, _ := decoded.Get("id")
+	assert.Equal(t, "some-user", id)
+}
+
+func TestShouldRefuseUnframedMessage(t *testing.T) {
+	consumer := AvroConsumer{}
+
+	_, err := consumer.AvroDecode(&sarama.ConsumerMessage{Value: []byte("plain")})
+
+	assert.Error(t, err)
+}
@@ -7,6 +7,8 @@ import (
 type Consumer struct {
 	Ready  chan bool
 	Action Action
+	// Dedup, when given, skips the messages already processed.
+	Dedup *Dedup
 }
 
 func (consumer *Consumer) IsReady() chan bool {
@@ -24,7 +26,7 @@ func (consumer *Consumer) getAction() Action {
 // Setup is run at the beginning of a new session, before ConsumeClaim
 func (consumer *Consumer) Setup(sarama.ConsumerGroupSession) error {
 	// Mark the consumer as ready
-	close(consumer.Ready)
+	markReady(consumer.Ready)
 
 	return nil
 }
@@ -41,7 +43,9 @@ func (consumer *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	// The `ConsumeClaim` itself is called within a goroutine, see:
 	// https://github.com/Shopify/sarama/blob/master/consumer_group.go#L27-L29
 	for message := range claim.Messages() {
-		err := consumer.Action(message)
+		err := consumer.Dedup.process(message, func() error {
+			return consumer.Action(message)
+		})
 		if err != nil {
 			return err
 		}
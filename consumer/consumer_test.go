@@ -13,6 +13,13 @@ func addMessage(message *sarama.ConsumerMessage, c chan *sarama.ConsumerMessage)
 	c <- message
 }
 
+// addMessages sends the messages in order, unlike one addMessage goroutine by message.
+func addMessages(c chan *sarama.ConsumerMessage, messages ...*sarama.ConsumerMessage) {
+	for _, message := range messages {
+		c <- message
+	}
+}
+
 func TestGetSaleOrderFromAvroRecordWithFewAttributes(t *testing.T) {
 	// Set
 	controller := gomock.NewController(t)
@@ -55,3 +62,29 @@ func TestGetSaleOrderFromAvroRecordWithFewAttributes(t *testing.T) {
 	// Assertions
 	assert.Equal(t, errors.New("Invalid Partition"), error)
 }
+
+func TestShouldSetupWithoutReadyChannel(t *testing.T) {
+	// Set
+	consumer := Consumer{}
+
+	// Actions
+	err := consumer.Setup(nil)
+
+	// Assertions
+	assert.Nil(t, err)
+}
+
+func TestShouldSetupTwiceOnTheSameReadyChannel(t *testing.T) {
+	// Set
+	consumer := Consumer{Ready: make(chan bool)}
+
+	// Actions
+	firstErr := consumer.Setup(nil)
+	secondErr := consumer.Setup(nil)
+
+	// Assertions
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	_, open := <-consumer.IsReady()
+	assert.False(t, open)
+}
@@ -0,0 +1,87 @@
+package consumer
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// DedupStore remembers the IDs of the messages already processed, e.g: the
+// in-memory and the file-backed stores of the dedup package.
+type DedupStore interface {
+	// Seen tells if a message with the ID was already processed.
+	Seen(id string) (bool, error)
+
+	// Remember records the ID as processed.
+	Remember(id string) error
+}
+
+// MessageID derives the ID a message is deduplicated by.
+type MessageID func(message *sarama.ConsumerMessage) string
+
+// OffsetID identifies the message by its topic, partition and offset, telling
+// apart only the messages delivered again, e.g: after a rebalance.
+func OffsetID(message *sarama.ConsumerMessage) string {
+	return fmt.Sprintf("%s/%d/%d", message.Topic, message.Partition, message.Offset)
+}
+
+// KeyID identifies the message by its key, or by OffsetID when it has none.
+func KeyID(message *sarama.ConsumerMessage) string {
+	if len(message.Key) == 0 {
+		return OffsetID(message)
+	}
+
+	return string(message.Key)
+}
+
+// HeaderID identifies the message by the value of the given header,
+// or by OffsetID when it has none, e.g: HeaderID("message-id").
+func HeaderID(name string) MessageID {
+	return func(message *sarama.ConsumerMessage) string {
+		for _, header := range message.Headers {
+			if header != nil && string(header.Key) == name {
+				return string(header.Value)
+			}
+		}
+
+		return OffsetID(message)
+	}
+}
+
+// Dedup skips the messages whose ID the Store has already seen, so the action of
+// Consumer and AvroConsumer runs once by message even when it is delivered again.
+type Dedup struct {
+	Store DedupStore
+	// ID derives the message ID, OffsetID when not given.
+	ID MessageID
+}
+
+// process runs the action unless the message was already processed, remembering it once done.
+func (dedup *Dedup) process(message *sarama.ConsumerMessage, action func() error) error {
+	if dedup == nil || dedup.Store == nil {
+		return action()
+	}
+
+	id := OffsetID(message)
+	if dedup.ID != nil {
+		id = dedup.ID(message)
+	}
+
+	seen, err := dedup.Store.Seen(id)
+	if err != nil {
+		return errors.Wrap(err, "Error checking the dedup store")
+	}
+
+	if seen {
+		log.Debug().Str("id", id).Msg("Skipping message already processed.")
+		return nil
+	}
+
+	if err := action(); err != nil {
+		return err
+	}
+
+	return errors.Wrap(dedup.Store.Remember(id), "Error remembering message on the dedup store")
+}
@@ -0,0 +1,74 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// seenStore is a DedupStore remembering the IDs on a map.
+type seenStore map[string]bool
+
+func (store seenStore) Seen(id string) (bool, error) {
+	return store[id], nil
+}
+
+func (store seenStore) Remember(id string) error {
+	store[id] = true
+	return nil
+}
+
+func TestShouldSkipMessagesAlreadyProcessed(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+	store := seenStore{}
+
+	var processed []string
+	consumer := Consumer{
+		Ready: make(chan bool),
+		Action: func(message *sarama.ConsumerMessage) error {
+			processed = append(processed, string(message.Value))
+			return nil
+		},
+		Dedup: &Dedup{Store: store, ID: HeaderID("message-id")},
+	}
+
+	header := func(id string) []*sarama.RecordHeader {
+		return []*sarama.RecordHeader{{Key: []byte("message-id"), Value: []byte(id)}}
+	}
+	first := &sarama.ConsumerMessage{Offset: 1, Value: []byte("first"), Headers: header("a")}
+	duplicate := &sarama.ConsumerMessage{Offset: 2, Value: []byte("duplicate"), Headers: header("a")}
+	second := &sarama.ConsumerMessage{Offset: 3, Value: []byte("second")}
+
+	messages := make(chan *sarama.ConsumerMessage, 3)
+	addMessages(messages, first, duplicate, second)
+	close(messages)
+
+	// Expectations
+	claim.EXPECT().Messages().Return(messages)
+	session.EXPECT().MarkMessage(gomock.Any(), "").Times(3)
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "second"}, processed)
+	assert.Equal(t, seenStore{"a": true, "/0/3": true}, store)
+}
+
+func TestMessageIDs(t *testing.T) {
+	message := &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 42}
+
+	assert.Equal(t, "orders/1/42", OffsetID(message))
+	assert.Equal(t, "orders/1/42", KeyID(message))
+	assert.Equal(t, "orders/1/42", HeaderID("message-id")(message))
+
+	message.Key = []byte("order-1")
+	assert.Equal(t, "order-1", KeyID(message))
+}
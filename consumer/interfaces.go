@@ -9,6 +9,12 @@ type AvroAction func(record *goavro.Record) error
 
 type Action func(record *sarama.ConsumerMessage) error
 
+// TransformAction handles a message, giving the messages to be produced from it.
+type TransformAction func(message *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error)
+
+// AckAction handles a message, acknowledging it with ack once done, which may happen after it returns.
+type AckAction func(message *sarama.ConsumerMessage, ack *Acknowledgement) error
+
 type ConsumerInterface interface {
 	// Setup is run at the beginning of a new session, before ConsumeClaim.
 	Setup(sarama.ConsumerGroupSession) error
@@ -21,8 +27,40 @@ type ConsumerInterface interface {
 	// Once the Messages() channel is closed, the Handler must finish its processing
 	// loop and exit.
 	ConsumeClaim(sarama.ConsumerGroupSession, sarama.ConsumerGroupClaim) error
+}
 
+// Readiness is the Ready channel handshake, kept for compatibility: the channel is
+// closed once the consumer is set up and a new one is set before each session.
+// The gokafka.Worker State and Events should be used instead.
+type Readiness interface {
 	IsReady() chan bool
-	
+
 	SetReady(ready chan bool)
 }
+
+// Configurer is implemented by consumers which need sarama settings of their own,
+// applied to a copy of the config before the consumer group is created, e.g: AckConsumer
+// turning auto commit off.
+type Configurer interface {
+	Configure(saramaConfig *sarama.Config)
+}
+
+// Acknowledger is implemented by consumers whose messages are only done once acknowledged,
+// which may be after ConsumeClaim returned, e.g: AckConsumer, so they are waited on when draining.
+type Acknowledger interface {
+	// Unacknowledged gives the offsets of the partition handed to the consumer and not acknowledged yet.
+	Unacknowledged(topic string, partition int32) []int64
+}
+
+// markReady closes the Ready channel, when there is one still open.
+func markReady(ready chan bool) {
+	if ready == nil {
+		return
+	}
+
+	select {
+	case <-ready:
+	default:
+		close(ready)
+	}
+}
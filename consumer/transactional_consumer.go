@@ -0,0 +1,191 @@
+package consumer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrProducerFenced is returned once the transactional producer can't be used anymore, e.g: fenced
+// by another producer with the same transactional id, so the consumer should be stopped.
+var ErrProducerFenced = errors.New("the transactional producer hit a fatal error, e.g: fenced by another instance with the same transactional id")
+
+// defaultTransactionRetries is how many times an aborted transaction is tried again.
+const defaultTransactionRetries = 3
+
+// NewTransactionalProducer creates a producer for the TransactionalConsumer, on the
+// same brokers and settings of the consumer config, with the given transactional id.
+// The id must be stable for the instance and unique among the instances, e.g: the pod name.
+func NewTransactionalProducer(consumerConfig *config.Config, transactionalID string) (sarama.AsyncProducer, error) {
+	producerConfig := *consumerConfig.Kafka
+	producerConfig.Producer.Idempotent = true
+	producerConfig.Producer.RequiredAcks = sarama.WaitForAll
+	producerConfig.Producer.Return.Errors = true
+	producerConfig.Producer.Transaction.ID = transactionalID
+	producerConfig.Net.MaxOpenRequests = 1
+
+	producer, err := sarama.NewAsyncProducer(strings.Split(consumerConfig.Brokers, ","), &producerConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating transactional producer")
+	}
+
+	return producer, nil
+}
+
+// NewTransactionalConsumer creates the TransactionalConsumer of the config group, with its producer.
+func NewTransactionalConsumer(consumerConfig *config.Config, transactionalID string, action TransformAction) (*TransactionalConsumer, error) {
+	producer, err := NewTransactionalProducer(consumerConfig, transactionalID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionalConsumer{
+		Ready:    make(chan bool),
+		Action:   action,
+		Producer: producer,
+		Group:    consumerConfig.ConsumerGroup,
+	}, nil
+}
+
+// TransactionalConsumer produces the messages returned by the Action and commits the
+// consumed offset in the same transaction, so each message is processed exactly once.
+// Aborted transactions are tried again up to Retries times, 3 when 0, and never when negative.
+type TransactionalConsumer struct {
+	Ready    chan bool
+	Action   TransformAction
+	Producer sarama.AsyncProducer
+	Group    string
+	Retries  int
+
+	// lock keeps one transaction at a time, as the claims share the producer.
+	lock    sync.Mutex
+	fatal   error
+	drained sync.Once
+}
+
+func (consumer *TransactionalConsumer) IsReady() chan bool {
+	return consumer.Ready
+}
+
+func (consumer *TransactionalConsumer) SetReady(ready chan bool) {
+	consumer.Ready = ready
+}
+
+// Configure reads only committed messages and turns sarama's auto commit off,
+// the offsets being committed within the transactions.
+func (consumer *TransactionalConsumer) Configure(saramaConfig *sarama.Config) {
+	saramaConfig.Consumer.IsolationLevel = sarama.ReadCommitted
+	saramaConfig.Consumer.Offsets.AutoCommit.Enable = false
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (consumer *TransactionalConsumer) Setup(sarama.ConsumerGroupSession) error {
+	consumer.lock.Lock()
+	fatal := consumer.fatal
+	consumer.lock.Unlock()
+	if fatal != nil {
+		return fatal
+	}
+
+	consumer.drained.Do(func() {
+		go consumer.drainErrors()
+	})
+
+	// Mark the consumer as ready
+	markReady(consumer.Ready)
+
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
+func (consumer *TransactionalConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
+func (consumer *TransactionalConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// NOTE:
+	// Do not move the code below to a goroutine.
+	// The `ConsumeClaim` itself is called within a goroutine, see:
+	// https://github.com/Shopify/sarama/blob/master/consumer_group.go#L27-L29
+	for message := range claim.Messages() {
+		outputs, err := consumer.Action(message)
+		if err != nil {
+			return err
+		}
+
+		if err := consumer.process(message, outputs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// process runs the transaction of the message, aborting and trying it again on abortable errors.
+func (consumer *TransactionalConsumer) process(message *sarama.ConsumerMessage, outputs []*sarama.ProducerMessage) error {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	if consumer.fatal != nil {
+		return consumer.fatal
+	}
+
+	retries := consumer.Retries
+	switch {
+	case retries == 0:
+		retries = defaultTransactionRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err = consumer.transaction(message, outputs); err == nil {
+			return nil
+		}
+
+		status := consumer.Producer.TxnStatus()
+		if status&sarama.ProducerTxnFlagFatalError != 0 {
+			consumer.fatal = fmt.Errorf("%w: %v", ErrProducerFenced, err)
+			return consumer.fatal
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt+1).Msg("Transaction failed, aborting it.")
+		if status&sarama.ProducerTxnFlagInTransaction != 0 || status&sarama.ProducerTxnFlagAbortableError != 0 {
+			if abortErr := consumer.Producer.AbortTxn(); abortErr != nil {
+				return errors.Wrap(abortErr, "Error aborting transaction")
+			}
+		}
+	}
+
+	return errors.Wrap(err, "Error processing message in a transaction")
+}
+
+func (consumer *TransactionalConsumer) transaction(message *sarama.ConsumerMessage, outputs []*sarama.ProducerMessage) error {
+	if err := consumer.Producer.BeginTxn(); err != nil {
+		return err
+	}
+
+	for _, output := range outputs {
+		consumer.Producer.Input() <- output
+	}
+
+	if err := consumer.Producer.AddMessageToTxn(message, consumer.Group, nil); err != nil {
+		return err
+	}
+
+	return consumer.Producer.CommitTxn()
+}
+
+// drainErrors logs the producer errors, which also fail the transaction they belong to.
+func (consumer *TransactionalConsumer) drainErrors() {
+	for err := range consumer.Producer.Errors() {
+		log.Error().Err(err).Msg("Error producing transactional message.")
+	}
+}
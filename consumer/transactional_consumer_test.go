@@ -0,0 +1,179 @@
+package consumer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// transactionalProducer records the transactions, failing the commits with the given errors.
+type transactionalProducer struct {
+	sarama.AsyncProducer
+	input        chan *sarama.ProducerMessage
+	commitErrors []error
+	status       []sarama.ProducerTxnStatusFlag
+	committed    []int64
+	produced     []*sarama.ProducerMessage
+	aborts       int
+}
+
+func (producer *transactionalProducer) BeginTxn() error {
+	return nil
+}
+
+func (producer *transactionalProducer) Input() chan<- *sarama.ProducerMessage {
+	return producer.input
+}
+
+func (producer *transactionalProducer) AddMessageToTxn(message *sarama.ConsumerMessage, group string, metadata *string) error {
+	producer.committed = append(producer.committed, message.Offset+1)
+	return nil
+}
+
+func (producer *transactionalProducer) CommitTxn() error {
+	for len(producer.input) > 0 {
+		producer.produced = append(producer.produced, <-producer.input)
+	}
+
+	if len(producer.commitErrors) == 0 {
+		return nil
+	}
+
+	err := producer.commitErrors[0]
+	producer.commitErrors = producer.commitErrors[1:]
+	return err
+}
+
+func (producer *transactionalProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	status := producer.status[0]
+	producer.status = producer.status[1:]
+	return status
+}
+
+func (producer *transactionalProducer) AbortTxn() error {
+	producer.aborts++
+	return nil
+}
+
+func TestShouldRetryAbortedTransactions(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+	producer := &transactionalProducer{
+		input:        make(chan *sarama.ProducerMessage, 10),
+		commitErrors: []error{errors.New("coordinator not available")},
+		status:       []sarama.ProducerTxnStatusFlag{sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagAbortableError},
+	}
+
+	consumer := TransactionalConsumer{
+		Ready: make(chan bool),
+		Action: func(message *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error) {
+			return []*sarama.ProducerMessage{{Topic: "orders-enriched", Value: sarama.ByteEncoder(message.Value)}}, nil
+		},
+		Producer: producer,
+		Group:    "group",
+	}
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: 10, Value: []byte("order")}
+	close(messages)
+
+	// Expectations
+	claim.EXPECT().Messages().Return(messages)
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.Equal(t, 1, producer.aborts)
+	assert.Equal(t, []int64{11, 11}, producer.committed)
+	assert.Len(t, producer.produced, 2)
+}
+
+func TestShouldNotRetryWhenRetriesAreNegative(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+	producer := &transactionalProducer{
+		input:        make(chan *sarama.ProducerMessage, 10),
+		commitErrors: []error{errors.New("coordinator not available")},
+		status:       []sarama.ProducerTxnStatusFlag{sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagAbortableError},
+	}
+
+	consumer := TransactionalConsumer{
+		Ready: make(chan bool),
+		Action: func(message *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error) {
+			return nil, nil
+		},
+		Producer: producer,
+		Group:    "group",
+		Retries:  -1,
+	}
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: 10}
+
+	// Expectations
+	claim.EXPECT().Messages().Return(messages)
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+
+	// Assertions
+	assert.EqualError(t, err, "Error processing message in a transaction: coordinator not available")
+	assert.Equal(t, 1, producer.aborts)
+	assert.Equal(t, []int64{11}, producer.committed)
+}
+
+func TestShouldStopWhenTheProducerIsFenced(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := mocks.NewMockConsumerGroupClaim(controller)
+	session := mocks.NewMockConsumerGroupSession(controller)
+	producer := &transactionalProducer{
+		input:        make(chan *sarama.ProducerMessage, 10),
+		commitErrors: []error{sarama.ErrProducerFenced},
+		status:       []sarama.ProducerTxnStatusFlag{sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagFatalError},
+	}
+
+	consumer := TransactionalConsumer{
+		Ready: make(chan bool),
+		Action: func(message *sarama.ConsumerMessage) ([]*sarama.ProducerMessage, error) {
+			return nil, nil
+		},
+		Producer: producer,
+		Group:    "group",
+	}
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: 10}
+
+	// Expectations
+	claim.EXPECT().Messages().Return(messages)
+
+	// Actions
+	err := consumer.ConsumeClaim(session, claim)
+	setupErr := consumer.Setup(session)
+
+	// Assertions
+	assert.True(t, errors.Is(err, ErrProducerFenced))
+	assert.True(t, errors.Is(setupErr, ErrProducerFenced))
+	assert.Equal(t, 0, producer.aborts)
+}
+
+func TestShouldReadCommittedMessagesOnly(t *testing.T) {
+	saramaConfig := sarama.NewConfig()
+	consumer := TransactionalConsumer{}
+
+	consumer.Configure(saramaConfig)
+
+	assert.Equal(t, sarama.ReadCommitted, saramaConfig.Consumer.IsolationLevel)
+	assert.False(t, saramaConfig.Consumer.Offsets.AutoCommit.Enable)
+}
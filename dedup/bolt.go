@@ -0,0 +1,152 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("processed")
+
+// Bolt is a DedupStore kept on a bbolt file, so the processed IDs survive restarts.
+// Each ID is kept for TTL, the expired ones being purged when the store is opened,
+// and then on Remember once every TTL, so the file doesn't grow on long runs.
+type Bolt struct {
+	db  *bolt.DB
+	ttl time.Duration
+	now func() time.Time
+
+	lock   sync.Mutex
+	purged time.Time
+}
+
+// OpenBolt opens, or creates, the store at path, keeping each ID for the TTL, forever when zero.
+func OpenBolt(path string, ttl time.Duration) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error opening dedup store")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "Error creating dedup store bucket")
+	}
+
+	store := &Bolt{db: db, ttl: ttl, now: time.Now}
+	if err := store.Purge(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *Bolt) Seen(id string) (bool, error) {
+	seen := false
+	err := store.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltBucket).Get([]byte(id))
+		seen = value != nil && !store.expired(value)
+		return nil
+	})
+
+	return seen, err
+}
+
+func (store *Bolt) Remember(id string) error {
+	var expires int64
+	if store.ttl > 0 {
+		expires = store.now().Add(store.ttl).UnixNano()
+	}
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(expires))
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(id), value)
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.purgeEveryTTL()
+}
+
+// purgeEveryTTL purges the expired IDs when the last purge is older than the TTL.
+func (store *Bolt) purgeEveryTTL() error {
+	if store.ttl <= 0 {
+		return nil
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if store.now().Before(store.purged.Add(store.ttl)) {
+		return nil
+	}
+
+	return store.purge()
+}
+
+// Purge removes the expired IDs.
+func (store *Bolt) Purge() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.purge()
+}
+
+func (store *Bolt) purge() error {
+	purged := store.now()
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		// Deleting while iterating skips keys on bbolt, so they are collected first.
+		var expired [][]byte
+		err := bucket.ForEach(func(key []byte, value []byte) error {
+			if store.expired(value) {
+				expired = append(expired, append([]byte{}, key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Error purging dedup store")
+	}
+
+	store.purged = purged
+
+	return nil
+}
+
+func (store *Bolt) Close() error {
+	return store.db.Close()
+}
+
+func (store *Bolt) expired(value []byte) bool {
+	if len(value) != 8 {
+		return true
+	}
+
+	expires := int64(binary.BigEndian.Uint64(value))
+
+	return expires != 0 && store.now().UnixNano() >= expires
+}
@@ -0,0 +1,74 @@
+package dedup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltRemembersAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.db")
+
+	store, err := OpenBolt(path, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Remember("orders/0/10"))
+	assert.Nil(t, store.Close())
+
+	store, err = OpenBolt(path, time.Hour)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	seen, err := store.Seen("orders/0/10")
+	assert.Nil(t, err)
+	assert.True(t, seen)
+
+	seen, err = store.Seen("orders/0/11")
+	assert.Nil(t, err)
+	assert.False(t, seen)
+}
+
+func TestBoltPurgesExpired(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	store, err := OpenBolt(filepath.Join(t.TempDir(), "dedup.db"), time.Minute)
+	assert.Nil(t, err)
+	defer store.Close()
+	store.now = func() time.Time { return now }
+
+	assert.Nil(t, store.Remember("a"))
+	assert.Nil(t, store.Remember("b"))
+	now = now.Add(time.Minute)
+
+	seen, _ := store.Seen("a")
+	assert.False(t, seen)
+	assert.Nil(t, store.Purge())
+	assert.Nil(t, store.db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 0, tx.Bucket(boltBucket).Stats().KeyN)
+		return nil
+	}))
+}
+
+func TestBoltPurgesExpiredOnRememberEveryTTL(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	store, err := OpenBolt(filepath.Join(t.TempDir(), "dedup.db"), time.Minute)
+	assert.Nil(t, err)
+	defer store.Close()
+	store.now = func() time.Time { return now }
+	store.purged = now
+
+	assert.Nil(t, store.Remember("a"))
+	now = now.Add(30 * time.Second)
+	assert.Nil(t, store.Remember("b"))
+	now = now.Add(45 * time.Second)
+	assert.Nil(t, store.Remember("c"))
+
+	assert.Nil(t, store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		assert.Nil(t, bucket.Get([]byte("a")))
+		assert.NotNil(t, bucket.Get([]byte("b")))
+		assert.NotNil(t, bucket.Get([]byte("c")))
+		return nil
+	}))
+}
@@ -0,0 +1,87 @@
+// Package dedup holds the consumer.DedupStore implementations.
+package dedup
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory DedupStore keeping up to Size IDs, each for TTL,
+// forgetting the least recently seen ones first.
+type Memory struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type memoryEntry struct {
+	id      string
+	expires time.Time
+}
+
+// NewMemory creates a Memory store of the given size and TTL, with no expiry when the TTL is zero.
+func NewMemory(size int, ttl time.Duration) *Memory {
+	return &Memory{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (memory *Memory) Seen(id string) (bool, error) {
+	memory.lock.Lock()
+	defer memory.lock.Unlock()
+
+	element, ok := memory.entries[id]
+	if !ok {
+		return false, nil
+	}
+
+	if memory.expired(element.Value.(*memoryEntry)) {
+		memory.remove(element)
+		return false, nil
+	}
+
+	memory.order.MoveToFront(element)
+
+	return true, nil
+}
+
+func (memory *Memory) Remember(id string) error {
+	memory.lock.Lock()
+	defer memory.lock.Unlock()
+
+	var expires time.Time
+	if memory.ttl > 0 {
+		expires = memory.now().Add(memory.ttl)
+	}
+
+	if element, ok := memory.entries[id]; ok {
+		element.Value.(*memoryEntry).expires = expires
+		memory.order.MoveToFront(element)
+		return nil
+	}
+
+	memory.entries[id] = memory.order.PushFront(&memoryEntry{id: id, expires: expires})
+	for memory.size > 0 && memory.order.Len() > memory.size {
+		memory.remove(memory.order.Back())
+	}
+
+	return nil
+}
+
+func (memory *Memory) expired(entry *memoryEntry) bool {
+	return !entry.expires.IsZero() && !memory.now().Before(entry.expires)
+}
+
+func (memory *Memory) remove(element *list.Element) {
+	memory.order.Remove(element)
+	delete(memory.entries, element.Value.(*memoryEntry).id)
+}
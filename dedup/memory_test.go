@@ -0,0 +1,39 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryForgetsLeastRecentlySeen(t *testing.T) {
+	memory := NewMemory(2, 0)
+
+	assert.Nil(t, memory.Remember("a"))
+	assert.Nil(t, memory.Remember("b"))
+	seen, _ := memory.Seen("a")
+	assert.True(t, seen)
+	assert.Nil(t, memory.Remember("c"))
+
+	seenA, _ := memory.Seen("a")
+	seenB, _ := memory.Seen("b")
+	seenC, _ := memory.Seen("c")
+	assert.True(t, seenA)
+	assert.False(t, seenB)
+	assert.True(t, seenC)
+}
+
+func TestMemoryForgetsExpired(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	memory := NewMemory(10, time.Minute)
+	memory.now = func() time.Time { return now }
+
+	assert.Nil(t, memory.Remember("a"))
+	seen, _ := memory.Seen("a")
+	assert.True(t, seen)
+
+	now = now.Add(time.Minute)
+	seen, _ = memory.Seen("a")
+	assert.False(t, seen)
+}
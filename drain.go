@@ -0,0 +1,285 @@
+package gokafka
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/consumer"
+	"github.com/leroy-merlin-br/gokafka/offsets"
+	"github.com/pkg/errors"
+)
+
+// ErrDrainTimeout is returned when the in-flight messages aren't done within the drain timeout.
+var ErrDrainTimeout = errors.New("the in-flight messages weren't done within the drain timeout")
+
+// defaultDrainTimeout is below the 30s Kubernetes gives before killing the pod.
+const defaultDrainTimeout = 25 * time.Second
+
+// ackPollInterval is how often the acknowledgements are checked while draining.
+const ackPollInterval = 10 * time.Millisecond
+
+// drainer hands the claimed messages to the consumer one at a time, so once
+// draining only the messages already handed, the in-flight ones, are finished.
+// It also tracks the claims progress, for the health of the worker.
+type drainer struct {
+	lock     sync.Mutex
+	draining chan struct{}
+	drained  sync.Once
+	inFlight map[string]*sarama.ConsumerMessage
+	session  sarama.ConsumerGroupSession
+	claims   map[string]sarama.ConsumerGroupClaim
+	next     map[string]int64
+	lastPoll time.Time
+	// processed counts the messages done with no error, since the last session.
+	processed int
+	// abandoned is closed once the drain timed out, the acknowledgements being no longer waited on.
+	abandoned     chan struct{}
+	abandonedOnce sync.Once
+}
+
+func newDrainer() *drainer {
+	return &drainer{
+		draining:  make(chan struct{}),
+		inFlight:  map[string]*sarama.ConsumerMessage{},
+		claims:    map[string]sarama.ConsumerGroupClaim{},
+		next:      map[string]int64{},
+		abandoned: make(chan struct{}),
+	}
+}
+
+// drainingClaim is a claim whose messages come through the drainer.
+type drainingClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (claim *drainingClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return claim.messages
+}
+
+// claim wraps the claim, the returned func must be called with the error of the consumer once done with it.
+func (drainer *drainer) claim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) (*drainingClaim, func(err error)) {
+	key := claimKey(claim.Topic(), claim.Partition())
+
+	drainer.lock.Lock()
+	drainer.session = session
+	drainer.claims[key] = claim
+	drainer.next[key] = claim.InitialOffset()
+	drainer.lastPoll = time.Now()
+	drainer.lock.Unlock()
+
+	wrapped := &drainingClaim{ConsumerGroupClaim: claim, messages: make(chan *sarama.ConsumerMessage)}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(wrapped.messages)
+		for {
+			select {
+			case <-drainer.draining:
+				return
+			default:
+			}
+
+			select {
+			case <-drainer.draining:
+				return
+			case <-done:
+				return
+			case message, ok := <-claim.Messages():
+				if !ok {
+					return
+				}
+
+				select {
+				case wrapped.messages <- message:
+					drainer.handed(key, message)
+				case <-drainer.draining:
+					return
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	return wrapped, func(err error) {
+		close(done)
+		if err == nil {
+			drainer.handed(key, nil)
+		}
+
+		drainer.lock.Lock()
+		delete(drainer.inFlight, key)
+		drainer.lock.Unlock()
+
+		drainer.lock.Lock()
+		delete(drainer.claims, key)
+		delete(drainer.next, key)
+		drainer.lock.Unlock()
+	}
+}
+
+// handed tracks the message the consumer is on, for the partition of the key,
+// nil once it is done with the partition. The previous message is then done.
+func (drainer *drainer) handed(key string, message *sarama.ConsumerMessage) {
+	drainer.lock.Lock()
+	defer drainer.lock.Unlock()
+
+	if _, ok := drainer.inFlight[key]; ok {
+		drainer.processed++
+	}
+
+	if message == nil {
+		delete(drainer.inFlight, key)
+		return
+	}
+
+	drainer.inFlight[key] = message
+	drainer.next[key] = message.Offset + 1
+	drainer.lastPoll = time.Now()
+}
+
+// progress gives the assigned partitions, their lag and when the last message was handed.
+// The lag of a partition is left out until its position is known.
+func (drainer *drainer) progress() (map[string][]int32, offsets.Offsets, time.Time) {
+	drainer.lock.Lock()
+	defer drainer.lock.Unlock()
+
+	assignments := map[string][]int32{}
+	lag := offsets.Offsets{}
+	for key, claim := range drainer.claims {
+		assignments[claim.Topic()] = append(assignments[claim.Topic()], claim.Partition())
+
+		if next := drainer.next[key]; next >= 0 {
+			if lag[claim.Topic()] == nil {
+				lag[claim.Topic()] = map[int32]int64{}
+			}
+			lag[claim.Topic()][claim.Partition()] = claim.HighWaterMarkOffset() - next
+		}
+	}
+
+	for _, partitions := range assignments {
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	}
+
+	return assignments, lag, drainer.lastPoll
+}
+
+// sessionProcessed tells whether messages were done with no error since the last call.
+func (drainer *drainer) sessionProcessed() bool {
+	drainer.lock.Lock()
+	defer drainer.lock.Unlock()
+
+	processed := drainer.processed > 0
+	drainer.processed = 0
+
+	return processed
+}
+
+// drain stops handing messages, the consumers finishing the in-flight ones.
+func (drainer *drainer) drain() {
+	drainer.drained.Do(func() {
+		close(drainer.draining)
+	})
+}
+
+func (drainer *drainer) isDraining() bool {
+	select {
+	case <-drainer.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// abandon commits the offsets marked so far, giving the messages still in flight.
+func (drainer *drainer) abandon() []*sarama.ConsumerMessage {
+	drainer.lock.Lock()
+	defer drainer.lock.Unlock()
+
+	drainer.abandonedOnce.Do(func() {
+		close(drainer.abandoned)
+	})
+
+	if drainer.session != nil {
+		drainer.session.Commit()
+	}
+
+	abandoned := make([]*sarama.ConsumerMessage, 0, len(drainer.inFlight))
+	for _, message := range drainer.inFlight {
+		abandoned = append(abandoned, message)
+	}
+
+	return abandoned
+}
+
+func claimKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
+
+// waitAcknowledged waits, once draining, for the messages of the claim the consumer acknowledges
+// after returning, see consumer.Acknowledger, until they are acknowledged or abandoned.
+func (handler *handler) waitAcknowledged(claim sarama.ConsumerGroupClaim) {
+	acknowledger, ok := handler.ConsumerInterface.(consumer.Acknowledger)
+	if !ok || !handler.drainer.isDraining() {
+		return
+	}
+
+	// The consumer returned, its last message is no longer in flight but waiting for its ack.
+	handler.drainer.handed(claimKey(claim.Topic(), claim.Partition()), nil)
+
+	ticker := time.NewTicker(ackPollInterval)
+	defer ticker.Stop()
+
+	for len(acknowledger.Unacknowledged(claim.Topic(), claim.Partition())) > 0 {
+		select {
+		case <-handler.drainer.abandoned:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// abandon commits the offsets marked so far, giving the messages still in flight
+// and those waiting for their ack.
+func (handler *handler) abandon() []*sarama.ConsumerMessage {
+	unacknowledged := handler.unacknowledged()
+	abandoned := handler.drainer.abandon()
+
+	inFlight := map[string]bool{}
+	for _, message := range abandoned {
+		inFlight[fmt.Sprintf("%s/%d", claimKey(message.Topic, message.Partition), message.Offset)] = true
+	}
+
+	for _, message := range unacknowledged {
+		if !inFlight[fmt.Sprintf("%s/%d", claimKey(message.Topic, message.Partition), message.Offset)] {
+			abandoned = append(abandoned, message)
+		}
+	}
+
+	return abandoned
+}
+
+// unacknowledged gives the messages of the assigned partitions not acknowledged yet, see consumer.Acknowledger.
+func (handler *handler) unacknowledged() []*sarama.ConsumerMessage {
+	acknowledger, ok := handler.ConsumerInterface.(consumer.Acknowledger)
+	if !ok {
+		return nil
+	}
+
+	assignments, _, _ := handler.drainer.progress()
+
+	var messages []*sarama.ConsumerMessage
+	for topic, partitions := range assignments {
+		for _, partition := range partitions {
+			for _, offset := range acknowledger.Unacknowledged(topic, partition) {
+				messages = append(messages, &sarama.ConsumerMessage{Topic: topic, Partition: partition, Offset: offset})
+			}
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,268 @@
+package gokafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/consumer"
+	consumerMocks "github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// pausedGroup is a consumer group which only tells whether it was paused.
+type pausedGroup struct {
+	sarama.ConsumerGroup
+	paused bool
+}
+
+func (group *pausedGroup) PauseAll() {
+	group.paused = true
+}
+
+func TestShouldStopHandingMessagesOnceDraining(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := consumerMocks.NewMockConsumerGroupClaim(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	drainer := newDrainer()
+
+	messages := make(chan *sarama.ConsumerMessage, 2)
+	first := &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 10}
+	messages <- first
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 11}
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders")
+	claim.EXPECT().Partition().Return(int32(1))
+	claim.EXPECT().InitialOffset().Return(int64(10))
+	claim.EXPECT().Messages().Return(messages).AnyTimes()
+	session.EXPECT().Commit().Times(2)
+
+	// Actions
+	wrapped, done := drainer.claim(session, claim)
+	handed := <-wrapped.Messages()
+	drainer.drain()
+	_, open := <-wrapped.Messages()
+	abandoned := drainer.abandon()
+	done(nil)
+
+	// Assertions
+	assert.Equal(t, first, handed)
+	assert.False(t, open)
+	assert.Equal(t, []*sarama.ConsumerMessage{first}, abandoned)
+	assert.Empty(t, drainer.abandon())
+}
+
+func TestShouldReturnOnceTheInFlightMessagesAreDone(t *testing.T) {
+	// Set
+	worker := NewWorker(&config.Config{}, nil)
+	handler := newHandler(nil, nil, config.Config{}, []Option{WithDrainTimeout(time.Second)})
+	group := &pausedGroup{}
+	ctx, cancel := context.WithCancel(context.Background())
+	consumed := make(chan error, 1)
+
+	go func() {
+		<-ctx.Done()
+		consumed <- nil
+	}()
+
+	// Actions
+	err := worker.shutdown(group, handler, cancel, consumed)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.True(t, group.paused)
+	assert.Equal(t, Stopping, worker.State())
+}
+
+func TestShouldAbandonTheInFlightMessagesOnDrainTimeout(t *testing.T) {
+	// Set
+	worker := NewWorker(&config.Config{}, nil)
+	handler := newHandler(nil, nil, config.Config{}, []Option{WithDrainTimeout(10 * time.Millisecond)})
+	handler.drainer.handed("orders/1", &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 10})
+	_, cancel := context.WithCancel(context.Background())
+
+	// Actions
+	err := worker.shutdown(&pausedGroup{}, handler, cancel, make(chan error))
+
+	// Assertions
+	assert.True(t, errors.Is(err, ErrDrainTimeout))
+	assert.EqualError(t, err, "the in-flight messages weren't done within the drain timeout, 1 messages abandoned")
+}
+
+// newAckingHandler is a handler of an AckConsumer giving the acknowledgements of its messages.
+func newAckingHandler(options ...Option) (*handler, chan *consumer.Acknowledgement) {
+	acks := make(chan *consumer.Acknowledgement, 1)
+	ackConsumer := &consumer.AckConsumer{
+		Action: func(message *sarama.ConsumerMessage, ack *consumer.Acknowledgement) error {
+			acks <- ack
+			return nil
+		},
+	}
+
+	return newHandler(ackConsumer, nil, config.Config{}, options), acks
+}
+
+func TestShouldWaitForTheAcknowledgementsWhileDraining(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := consumerMocks.NewMockConsumerGroupClaim(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	handler, acks := newAckingHandler()
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 10}
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders").AnyTimes()
+	claim.EXPECT().Partition().Return(int32(1)).AnyTimes()
+	claim.EXPECT().InitialOffset().Return(int64(10))
+	claim.EXPECT().Messages().Return(messages).AnyTimes()
+	session.EXPECT().MarkOffset("orders", int32(1), int64(11), "")
+
+	// Actions
+	returned := make(chan error, 1)
+	go func() {
+		returned <- handler.ConsumeClaim(session, claim)
+	}()
+	ack := <-acks
+	handler.drainer.drain()
+
+	// Assertions
+	select {
+	case <-returned:
+		t.Fatal("the claim was done before its message was acknowledged")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ack.Ack()
+	assert.Nil(t, <-returned)
+}
+
+func TestShouldAbandonTheMessagesWaitingForTheirAckOnDrainTimeout(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := consumerMocks.NewMockConsumerGroupClaim(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	worker := NewWorker(&config.Config{}, nil)
+	handler, acks := newAckingHandler(WithDrainTimeout(50 * time.Millisecond))
+	_, cancel := context.WithCancel(context.Background())
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 10}
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders").AnyTimes()
+	claim.EXPECT().Partition().Return(int32(1)).AnyTimes()
+	claim.EXPECT().InitialOffset().Return(int64(10))
+	claim.EXPECT().HighWaterMarkOffset().Return(int64(11)).AnyTimes()
+	claim.EXPECT().Messages().Return(messages).AnyTimes()
+	session.EXPECT().Commit()
+
+	// Actions
+	consumed := make(chan error, 1)
+	go func() {
+		consumed <- handler.ConsumeClaim(session, claim)
+	}()
+	<-acks
+	err := worker.shutdown(&pausedGroup{}, handler, cancel, consumed)
+
+	// Assertions
+	assert.EqualError(t, err, "the in-flight messages weren't done within the drain timeout, 1 messages abandoned")
+	select {
+	case err := <-consumed:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the claim is still waiting for the acknowledgements once abandoned")
+	}
+}
+
+// newGroupBroker is a broker assigning the partition 0 of orders, with one message, to the group.
+func newGroupBroker(t *testing.T) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "group", broker),
+		"JoinGroupRequest": sarama.NewMockJoinGroupResponse(t).
+			SetGroupProtocol(sarama.RangeBalanceStrategyName).
+			SetMemberId("member").
+			SetLeaderId("leader"),
+		"SyncGroupRequest": sarama.NewMockSyncGroupResponse(t).
+			SetMemberAssignment(&sarama.ConsumerGroupMemberAssignment{Topics: map[string][]int32{"orders": {0}}}),
+		"HeartbeatRequest": sarama.NewMockHeartbeatResponse(t),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("group", "orders", 0, 0, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetOldest, 0).
+			SetOffset("orders", 0, sarama.OffsetNewest, 1),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+			SetMessage("orders", 0, 0, sarama.StringEncoder("order")),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"LeaveGroupRequest":   sarama.NewMockLeaveGroupResponse(t),
+	})
+
+	return broker
+}
+
+// stuckConsumer never returns from the first message it gets, until released.
+type stuckConsumer struct {
+	received chan struct{}
+	release  chan struct{}
+}
+
+func (consumer *stuckConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (consumer *stuckConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (consumer *stuckConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for range claim.Messages() {
+		close(consumer.received)
+		<-consumer.release
+	}
+
+	return nil
+}
+
+func TestShouldReturnWithinTheDrainTimeoutWhenAMessageIsStuck(t *testing.T) {
+	// Set
+	broker := newGroupBroker(t)
+	defer broker.Close()
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.V2_1_0_0
+	consumer := &stuckConsumer{received: make(chan struct{}), release: make(chan struct{})}
+	defer close(consumer.release)
+
+	worker := NewWorker(&config.Config{Kafka: saramaConfig, Brokers: broker.Addr(), ConsumerGroup: "group", Topic: "orders"},
+		consumer, WithDrainTimeout(100*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan error, 1)
+
+	// Actions
+	go func() {
+		returned <- worker.Run(ctx)
+	}()
+	<-consumer.received
+	cancel()
+
+	// Assertions
+	select {
+	case err := <-returned:
+		assert.True(t, errors.Is(err, ErrDrainTimeout))
+		assert.Equal(t, Stopped, worker.State())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run is still waiting on the stuck message past the drain timeout")
+	}
+}
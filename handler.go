@@ -0,0 +1,216 @@
+package gokafka
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/consumer"
+	"github.com/leroy-merlin-br/gokafka/offsets"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// handler wraps the consumer, doing the group work which needs the client,
+// e.g: starting partitions with no committed offset at the config StartTime.
+type handler struct {
+	consumer.ConsumerInterface
+	client         sarama.Client
+	consumerConfig config.Config
+	onAssigned     []RebalanceHook
+	onRevoked      []RebalanceHook
+	// transition, when set, follows the sessions of the consumer.
+	transition   func(state State)
+	drainer      *drainer
+	drainTimeout time.Duration
+	backoff      Backoff
+	onRestart    []func(restart Restart)
+
+	healthAddr        string
+	healthPollTimeout time.Duration
+	rotationInterval  time.Duration
+	rebuild           func() (*config.Config, error)
+
+	lock sync.Mutex
+	// started tells the session got running, failure is its first claim error, which sarama only logs.
+	started bool
+	failure error
+}
+
+func newHandler(handled consumer.ConsumerInterface, client sarama.Client, consumerConfig config.Config, options []Option) *handler {
+	handler := &handler{
+		ConsumerInterface: handled,
+		client:            client,
+		consumerConfig:    consumerConfig,
+		drainer:           newDrainer(),
+		drainTimeout:      defaultDrainTimeout,
+		backoff:           DefaultBackoff,
+	}
+	for _, option := range options {
+		option(handler)
+	}
+
+	return handler
+}
+
+// configure gives a copy of the sarama config with the settings the consumer needs, when it
+// is a consumer.Configurer, leaving the given config untouched for the others sharing it.
+func configure(saramaConfig *sarama.Config, handled consumer.ConsumerInterface) *sarama.Config {
+	configured := *saramaConfig
+	if configurer, ok := handled.(consumer.Configurer); ok {
+		configurer.Configure(&configured)
+	}
+
+	return &configured
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (handler *handler) Setup(session sarama.ConsumerGroupSession) error {
+	if !handler.consumerConfig.StartTime.IsZero() {
+		if err := handler.startAtTime(session); err != nil {
+			return err
+		}
+	}
+
+	if err := handler.ConsumerInterface.Setup(session); err != nil {
+		return err
+	}
+
+	for _, hook := range handler.onAssigned {
+		if err := hook(session, session.Claims()); err != nil {
+			return errors.Wrap(err, "Error on the assigned partitions hook")
+		}
+	}
+
+	handler.lock.Lock()
+	handler.started = true
+	handler.lock.Unlock()
+
+	if handler.transition != nil {
+		handler.transition(Running)
+	}
+
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
+// but before the offsets are committed for the very last time.
+func (handler *handler) Cleanup(session sarama.ConsumerGroupSession) error {
+	if handler.transition != nil {
+		handler.transition(Rebalancing)
+	}
+
+	var errs []error
+	for _, hook := range handler.onRevoked {
+		if err := hook(session, session.Claims()); err != nil {
+			errs = append(errs, errors.Wrap(err, "Error on the revoked partitions hook"))
+		}
+	}
+
+	if err := handler.ConsumerInterface.Cleanup(session); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+
+	return cleanupErrors(errs)
+}
+
+// cleanupErrors are the errors of the revoked hooks and of the consumer cleanup, none
+// being dropped. Each of them can still be checked with errors.Is.
+type cleanupErrors []error
+
+func (errs cleanupErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return "Error cleaning up the session:\n- " + strings.Join(messages, "\n- ")
+}
+
+func (errs cleanupErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConsumeClaim hands the claimed messages to the consumer through the drainer, waiting
+// once draining for those the consumer acknowledges after returning.
+func (handler *handler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	drainingClaim, done := handler.drainer.claim(session, claim)
+
+	err := handler.ConsumerInterface.ConsumeClaim(session, drainingClaim)
+	if err == nil {
+		handler.waitAcknowledged(claim)
+	}
+	done(err)
+	if err != nil {
+		handler.lock.Lock()
+		if handler.failure == nil {
+			handler.failure = err
+		}
+		handler.lock.Unlock()
+	}
+
+	return err
+}
+
+// sessionResult tells how the sessions went since the last call: whether they made
+// progress, getting running with no claim error or doing messages, and the first claim error.
+func (handler *handler) sessionResult() (bool, error) {
+	handler.lock.Lock()
+	started, failure := handler.started, handler.failure
+	handler.started, handler.failure = false, nil
+	handler.lock.Unlock()
+
+	processed := handler.drainer.sessionProcessed()
+
+	return processed || (started && failure == nil), failure
+}
+
+// startAtTime moves the claimed partitions with no committed offset to the first
+// messages since the StartTime. As they have no offset yet, it is marked instead
+// of reset, since sarama only resets offsets backwards.
+func (handler *handler) startAtTime(session sarama.ConsumerGroupSession) error {
+	committed, err := offsets.Committed(handler.client, handler.consumerConfig.ConsumerGroup, session.Claims())
+	if err != nil {
+		return err
+	}
+
+	for topic, partitions := range session.Claims() {
+		var fresh []int32
+		for _, partition := range partitions {
+			if committed[topic][partition] < 0 {
+				fresh = append(fresh, partition)
+			}
+		}
+
+		if len(fresh) == 0 {
+			continue
+		}
+
+		start, err := offsets.ForTime(handler.client, topic, fresh, handler.consumerConfig.StartTime)
+		if err != nil {
+			return errors.Wrap(err, "Error resolving the start offsets")
+		}
+
+		for partition, offset := range start {
+			session.MarkOffset(topic, partition, offset, "")
+			log.Info().Str("topic", topic).Int32("partition", partition).Int64("offset", offset).Msg("Partition started at the start time.")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package gokafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/consumer"
+	consumerMocks "github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/leroy-merlin-br/gokafka/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldStartNewPartitionsAtTheStartTime(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	startTime := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	millis := startTime.UnixNano() / int64(time.Millisecond)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "group", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("group", "orders", 0, 42, "", sarama.ErrNoError).
+			SetOffset("group", "orders", 1, -1, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 1, millis, 7),
+	})
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.V2_1_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, saramaConfig)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	handler := newHandler(consumer, client, config.Config{ConsumerGroup: "group", StartTime: startTime}, nil)
+
+	// Expectations
+	session.EXPECT().Claims().Return(map[string][]int32{"orders": {0, 1}}).AnyTimes()
+	session.EXPECT().MarkOffset("orders", int32(1), int64(7), "")
+	consumer.EXPECT().Setup(session).Return(nil)
+
+	// Actions
+	err = handler.Setup(session)
+
+	// Assertions
+	assert.Nil(t, err)
+}
+
+func TestShouldRunTheAssignedHooksAfterTheConsumerSetup(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	claims := map[string][]int32{"orders": {0, 1}}
+
+	var assigned map[string][]int32
+	handler := newHandler(consumer, nil, config.Config{}, []Option{
+		OnAssigned(func(session sarama.ConsumerGroupSession, claims map[string][]int32) error {
+			assigned = claims
+			return nil
+		}),
+	})
+
+	// Expectations
+	session.EXPECT().Claims().Return(claims)
+	consumer.EXPECT().Setup(session).Return(nil)
+
+	// Actions
+	err := handler.Setup(session)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.Equal(t, claims, assigned)
+}
+
+func TestShouldFailTheSetupWhenAnAssignedHookFails(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+
+	handler := newHandler(consumer, nil, config.Config{}, []Option{
+		OnAssigned(func(sarama.ConsumerGroupSession, map[string][]int32) error {
+			return errors.New("cache unavailable")
+		}),
+	})
+
+	// Expectations
+	session.EXPECT().Claims().Return(map[string][]int32{"orders": {0}})
+	consumer.EXPECT().Setup(session).Return(nil)
+
+	// Actions
+	err := handler.Setup(session)
+
+	// Assertions
+	assert.EqualError(t, err, "Error on the assigned partitions hook: cache unavailable")
+}
+
+func TestShouldRunTheRevokedHooksBeforeTheConsumerCleanup(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	claims := map[string][]int32{"orders": {0}}
+
+	var calls []string
+	handler := newHandler(consumer, nil, config.Config{}, []Option{
+		OnRevoked(func(session sarama.ConsumerGroupSession, claims map[string][]int32) error {
+			calls = append(calls, "revoked")
+			session.MarkOffset("orders", 0, 10, "")
+			return nil
+		}),
+	})
+
+	// Expectations
+	session.EXPECT().Claims().Return(claims)
+	session.EXPECT().MarkOffset("orders", int32(0), int64(10), "")
+	consumer.EXPECT().Cleanup(session).DoAndReturn(func(sarama.ConsumerGroupSession) error {
+		calls = append(calls, "cleanup")
+		return nil
+	})
+
+	// Actions
+	err := handler.Cleanup(session)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"revoked", "cleanup"}, calls)
+}
+
+func TestShouldCleanupTheConsumerWhenARevokedHookFails(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+
+	handler := newHandler(consumer, nil, config.Config{}, []Option{
+		OnRevoked(func(sarama.ConsumerGroupSession, map[string][]int32) error {
+			return errors.New("flush failed")
+		}),
+	})
+
+	// Expectations
+	session.EXPECT().Claims().Return(map[string][]int32{"orders": {0}})
+	consumer.EXPECT().Cleanup(session).Return(nil)
+
+	// Actions
+	err := handler.Cleanup(session)
+
+	// Assertions
+	assert.EqualError(t, err, "Error on the revoked partitions hook: flush failed")
+}
+
+func TestShouldGiveEveryCleanupError(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	errCommit := errors.New("commit failed")
+
+	handler := newHandler(consumer, nil, config.Config{}, []Option{
+		OnRevoked(func(sarama.ConsumerGroupSession, map[string][]int32) error {
+			return errors.New("flush failed")
+		}),
+		OnRevoked(func(sarama.ConsumerGroupSession, map[string][]int32) error {
+			return errors.New("close failed")
+		}),
+	})
+
+	// Expectations
+	session.EXPECT().Claims().Return(map[string][]int32{"orders": {0}}).Times(2)
+	consumer.EXPECT().Cleanup(session).Return(errCommit)
+
+	// Actions
+	err := handler.Cleanup(session)
+
+	// Assertions
+	assert.EqualError(t, err, "Error cleaning up the session:\n"+
+		"- Error on the revoked partitions hook: flush failed\n"+
+		"- Error on the revoked partitions hook: close failed\n"+
+		"- commit failed")
+	assert.True(t, errors.Is(err, errCommit))
+}
+
+func TestShouldConfigureACopyOfTheSaramaConfig(t *testing.T) {
+	// Set
+	saramaConfig := sarama.NewConfig()
+
+	// Actions
+	configured := configure(saramaConfig, &consumer.AckConsumer{})
+
+	// Assertions
+	assert.False(t, configured.Consumer.Offsets.AutoCommit.Enable)
+	assert.True(t, saramaConfig.Consumer.Offsets.AutoCommit.Enable)
+}
@@ -0,0 +1,111 @@
+package gokafka
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/leroy-merlin-br/gokafka/offsets"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultPollTimeout is how long a lagging worker may go with no message before not being ready.
+const defaultPollTimeout = time.Minute
+
+// Status is the state of a worker, as served on /status.
+type Status struct {
+	State       string             `json:"state"`
+	Assignments map[string][]int32 `json:"assignments"`
+	Lag         offsets.Offsets    `json:"lag"`
+	LastPoll    *time.Time         `json:"last_poll,omitempty"`
+	LastError   string             `json:"last_error,omitempty"`
+	Healthy     bool               `json:"healthy"`
+	Ready       bool               `json:"ready"`
+}
+
+// Status tells the state of the worker, its assignments and their lag. A worker is healthy
+// until it is stopped or its client closed, and ready when running with partitions assigned
+// which are either caught up or got a message within the poll timeout.
+func (worker *Worker) Status(pollTimeout time.Duration) Status {
+	worker.lock.Lock()
+	state, client, handler, lastErr := worker.state, worker.client, worker.handler, worker.lastErr
+	worker.lock.Unlock()
+
+	status := Status{
+		State:       state.String(),
+		Assignments: map[string][]int32{},
+		Lag:         offsets.Offsets{},
+		Healthy:     state != Stopped && (client == nil || !client.Closed()),
+	}
+	if lastErr != nil {
+		status.LastError = lastErr.Error()
+	}
+
+	if handler == nil {
+		return status
+	}
+
+	assignments, lag, lastPoll := handler.drainer.progress()
+	status.Assignments, status.Lag = assignments, lag
+	if !lastPoll.IsZero() {
+		status.LastPoll = &lastPoll
+	}
+
+	caughtUp := true
+	for _, partitions := range lag {
+		for _, behind := range partitions {
+			caughtUp = caughtUp && behind <= 0
+		}
+	}
+
+	status.Ready = status.Healthy && state == Running && len(assignments) > 0 &&
+		(caughtUp || time.Since(lastPoll) <= pollTimeout)
+
+	return status
+}
+
+// NewHealthHandler serves the worker health for the Kubernetes probes: /healthz and /readyz
+// answer 200 when healthy and ready, 503 otherwise, and /status answers the Status as JSON.
+// A pollTimeout of 0 is 1m.
+func NewHealthHandler(worker *Worker, pollTimeout time.Duration) http.Handler {
+	if pollTimeout == 0 {
+		pollTimeout = defaultPollTimeout
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(writer http.ResponseWriter, request *http.Request) {
+		probe(writer, worker.Status(pollTimeout).Healthy)
+	})
+	mux.HandleFunc("/readyz", func(writer http.ResponseWriter, request *http.Request) {
+		probe(writer, worker.Status(pollTimeout).Ready)
+	})
+	mux.HandleFunc("/status", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(worker.Status(pollTimeout))
+	})
+
+	return mux
+}
+
+// serveHealth serves the health endpoints on the address, giving the func which stops serving them.
+func (worker *Worker) serveHealth(addr string, pollTimeout time.Duration) func() {
+	server := &http.Server{Addr: addr, Handler: NewHealthHandler(worker, pollTimeout)}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Str("addr", addr).Msg("Error serving the health endpoints.")
+		}
+	}()
+
+	return func() {
+		server.Close()
+	}
+}
+
+func probe(writer http.ResponseWriter, ok bool) {
+	if !ok {
+		http.Error(writer, "not ok", http.StatusServiceUnavailable)
+		return
+	}
+
+	writer.Write([]byte("ok"))
+}
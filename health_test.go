@@ -0,0 +1,146 @@
+package gokafka
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/config"
+	consumerMocks "github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/leroy-merlin-br/gokafka/offsets"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(handler http.Handler, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return recorder
+}
+
+func TestShouldBeHealthyButNotReadyWhileStarting(t *testing.T) {
+	// Set
+	health := NewHealthHandler(NewWorker(&config.Config{}, nil), 0)
+
+	// Actions
+	healthz := serve(health, "/healthz")
+	readyz := serve(health, "/readyz")
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, healthz.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, readyz.Code)
+}
+
+func TestShouldBeReadyWhenRunningWithAssignedPartitions(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := consumerMocks.NewMockConsumerGroupClaim(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	worker := NewWorker(&config.Config{}, nil)
+	worker.handler = newHandler(nil, nil, config.Config{}, nil)
+	worker.transition(Running)
+
+	messages := make(chan *sarama.ConsumerMessage, 1)
+	messages <- &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 10}
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders").AnyTimes()
+	claim.EXPECT().Partition().Return(int32(1)).AnyTimes()
+	claim.EXPECT().InitialOffset().Return(int64(10))
+	claim.EXPECT().HighWaterMarkOffset().Return(int64(20)).AnyTimes()
+	claim.EXPECT().Messages().Return(messages).AnyTimes()
+
+	wrapped, done := worker.handler.drainer.claim(session, claim)
+	defer done(nil)
+	<-wrapped.Messages()
+	assert.Eventually(t, func() bool {
+		return worker.Status(time.Minute).Lag["orders"][1] == 9
+	}, time.Second, time.Millisecond)
+
+	// Actions
+	readyz := serve(NewHealthHandler(worker, time.Minute), "/readyz")
+	status := worker.Status(time.Minute)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, readyz.Code)
+	assert.Equal(t, "running", status.State)
+	assert.Equal(t, map[string][]int32{"orders": {1}}, status.Assignments)
+	assert.Equal(t, offsets.Offsets{"orders": {1: 9}}, status.Lag)
+	assert.True(t, status.Ready)
+}
+
+func TestShouldNotBeReadyWhenLaggingWithNoRecentPoll(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	claim := consumerMocks.NewMockConsumerGroupClaim(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	worker := NewWorker(&config.Config{}, nil)
+	worker.handler = newHandler(nil, nil, config.Config{}, nil)
+	worker.transition(Running)
+
+	// Expectations
+	claim.EXPECT().Topic().Return("orders").AnyTimes()
+	claim.EXPECT().Partition().Return(int32(1)).AnyTimes()
+	claim.EXPECT().InitialOffset().Return(int64(10))
+	claim.EXPECT().HighWaterMarkOffset().Return(int64(20)).AnyTimes()
+	claim.EXPECT().Messages().Return(make(chan *sarama.ConsumerMessage)).AnyTimes()
+
+	_, done := worker.handler.drainer.claim(session, claim)
+	defer done(nil)
+	time.Sleep(5 * time.Millisecond)
+
+	// Actions
+	readyz := serve(NewHealthHandler(worker, time.Millisecond), "/readyz")
+
+	// Assertions
+	assert.Equal(t, http.StatusServiceUnavailable, readyz.Code)
+}
+
+func TestShouldServeTheStatusOfAStoppedWorker(t *testing.T) {
+	// Set
+	worker := NewWorker(&config.Config{}, nil)
+	worker.failed(errors.New("brokers down"))
+	worker.transition(Stopped)
+	health := NewHealthHandler(worker, 0)
+
+	// Actions
+	healthz := serve(health, "/healthz")
+	status := serve(health, "/status")
+
+	// Assertions
+	assert.Equal(t, http.StatusServiceUnavailable, healthz.Code)
+	assert.Equal(t, "application/json", status.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"state":"stopped","assignments":{},"lag":{},"last_error":"brokers down","healthy":false,"ready":false}`, status.Body.String())
+}
+
+func TestShouldServeTheHealthOnTheAddressUntilStopped(t *testing.T) {
+	// Set
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	worker := NewWorker(&config.Config{}, nil)
+
+	// Actions
+	stop := worker.serveHealth(addr, 0)
+
+	// Assertions
+	assert.Eventually(t, func() bool {
+		response, err := http.Get("http://" + addr + "/healthz")
+		if err != nil {
+			return false
+		}
+		response.Body.Close()
+
+		return response.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	stop()
+	_, err = http.Get("http://" + addr + "/healthz")
+	assert.NotNil(t, err)
+}
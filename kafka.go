@@ -2,81 +2,44 @@ package gokafka
 
 import (
 	"context"
-	"github.com/Shopify/sarama"
+	"os/signal"
+	"syscall"
+
 	"github.com/leroy-merlin-br/gokafka/config"
 	"github.com/leroy-merlin-br/gokafka/consumer"
-	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
-	"os"
-	"os/signal"
-	"strings"
-	"sync"
-	"syscall"
 )
 
-func Handle(consumer consumer.ConsumerInterface) (err error) {
+func Handle(consumer consumer.ConsumerInterface, options ...Option) (err error) {
 	consumerConfig, err := config.Make()
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	client, err := sarama.NewConsumerGroup(strings.Split(consumerConfig.Brokers, ","), consumerConfig.ConsumerGroup, consumerConfig.Kafka)
-	defer func() {
-		err = client.Close()
-	}()
-
-	if err != nil {
-		return errors.Wrap(err, "Error creating consumer group client: %v")
-	}
-	wg := &sync.WaitGroup{}
-	defer wg.Wait()
-	wg.Add(1)
-
-	occurancesErr := consume(client, wg, *consumerConfig, consumer, ctx)
-
-	<-consumer.IsReady() // Await till the consumer has been set up
-	log.Print("Consumer up and running!...")
+	return HandleWithConfig(consumerConfig, consumer, options...)
+}
 
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
-	select {
-	case <-ctx.Done():
-		log.Print("terminating: context cancelled")
-	case <-sigterm:
-		log.Print("terminating: via signal")
-	}
+// HandleWithConfig is Handle using the given config, e.g: one built with config.New.
+// It runs the consumer on a Worker until SIGINT or SIGTERM.
+func HandleWithConfig(consumerConfig *config.Config, consumer consumer.ConsumerInterface, options ...Option) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	var open bool
-	if err, open = <-occurancesErr; open {
-		return
-	}
+	worker := NewWorker(consumerConfig, consumer, options...)
+	go logStates(worker.Events())
 
-	return err
+	return worker.Run(ctx)
 }
 
-func consume(client sarama.ConsumerGroup, wg *sync.WaitGroup, consumerConfig config.Config, consumer consumer.ConsumerInterface, ctx context.Context) <-chan error {
-	errs := make(chan error, 1)
-	defer close(errs)
-
-	go func() {
-		defer wg.Done()
-		for {
-			// `Consume` should be called inside an infinite loop, when a
-			// server-side rebalance happens, the consumer session will need to be
-			// recreated to get the new claims
-			if err := client.Consume(ctx, strings.Split(consumerConfig.Topic, ","), consumer); err != nil {
-				errs <- errors.Wrap(err, "Error creating consumer group client: %v")
-			}
-			// check if context was cancelled, signaling that the consumer should stop
-			if ctx.Err() != nil {
-				return
-			}
-			consumer.SetReady(make(chan bool))
+func logStates(events <-chan State) {
+	for state := range events {
+		switch state {
+		case Running:
+			log.Print("Consumer up and running!...")
+		case Stopped:
+			log.Print("terminating: consumer stopped")
+		default:
+			log.Print("Consumer " + state.String() + "...")
 		}
-	}()
-
-	return errs
+	}
 }
@@ -1,7 +1,7 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: consumer/interfaces.go
 
-// Package mock_consumer is a generated GoMock package.
+// Package mocks is a generated GoMock package.
 package mocks
 
 import (
@@ -62,8 +62,45 @@ func (mr *MockConsumerInterfaceMockRecorder) ConsumeClaim(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClaim", reflect.TypeOf((*MockConsumerInterface)(nil).ConsumeClaim), arg0, arg1)
 }
 
+// Setup mocks base method.
+func (m *MockConsumerInterface) Setup(arg0 sarama.ConsumerGroupSession) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Setup", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Setup indicates an expected call of Setup.
+func (mr *MockConsumerInterfaceMockRecorder) Setup(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Setup", reflect.TypeOf((*MockConsumerInterface)(nil).Setup), arg0)
+}
+
+// MockReadiness is a mock of Readiness interface.
+type MockReadiness struct {
+	ctrl     *gomock.Controller
+	recorder *MockReadinessMockRecorder
+}
+
+// MockReadinessMockRecorder is the mock recorder for MockReadiness.
+type MockReadinessMockRecorder struct {
+	mock *MockReadiness
+}
+
+// NewMockReadiness creates a new mock instance.
+func NewMockReadiness(ctrl *gomock.Controller) *MockReadiness {
+	mock := &MockReadiness{ctrl: ctrl}
+	mock.recorder = &MockReadinessMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReadiness) EXPECT() *MockReadinessMockRecorder {
+	return m.recorder
+}
+
 // IsReady mocks base method.
-func (m *MockConsumerInterface) IsReady() chan bool {
+func (m *MockReadiness) IsReady() chan bool {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "IsReady")
 	ret0, _ := ret[0].(chan bool)
@@ -71,33 +108,54 @@ func (m *MockConsumerInterface) IsReady() chan bool {
 }
 
 // IsReady indicates an expected call of IsReady.
-func (mr *MockConsumerInterfaceMockRecorder) IsReady() *gomock.Call {
+func (mr *MockReadinessMockRecorder) IsReady() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReady", reflect.TypeOf((*MockConsumerInterface)(nil).IsReady))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReady", reflect.TypeOf((*MockReadiness)(nil).IsReady))
 }
 
 // SetReady mocks base method.
-func (m *MockConsumerInterface) SetReady(ready chan bool) {
+func (m *MockReadiness) SetReady(ready chan bool) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "SetReady", ready)
 }
 
 // SetReady indicates an expected call of SetReady.
-func (mr *MockConsumerInterfaceMockRecorder) SetReady(ready interface{}) *gomock.Call {
+func (mr *MockReadinessMockRecorder) SetReady(ready interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReady", reflect.TypeOf((*MockConsumerInterface)(nil).SetReady), ready)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReady", reflect.TypeOf((*MockReadiness)(nil).SetReady), ready)
 }
 
-// Setup mocks base method.
-func (m *MockConsumerInterface) Setup(arg0 sarama.ConsumerGroupSession) error {
+// MockConfigurer is a mock of Configurer interface.
+type MockConfigurer struct {
+	ctrl     *gomock.Controller
+	recorder *MockConfigurerMockRecorder
+}
+
+// MockConfigurerMockRecorder is the mock recorder for MockConfigurer.
+type MockConfigurerMockRecorder struct {
+	mock *MockConfigurer
+}
+
+// NewMockConfigurer creates a new mock instance.
+func NewMockConfigurer(ctrl *gomock.Controller) *MockConfigurer {
+	mock := &MockConfigurer{ctrl: ctrl}
+	mock.recorder = &MockConfigurerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConfigurer) EXPECT() *MockConfigurerMockRecorder {
+	return m.recorder
+}
+
+// Configure mocks base method.
+func (m *MockConfigurer) Configure(saramaConfig *sarama.Config) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Setup", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "Configure", saramaConfig)
 }
 
-// Setup indicates an expected call of Setup.
-func (mr *MockConsumerInterfaceMockRecorder) Setup(arg0 interface{}) *gomock.Call {
+// Configure indicates an expected call of Configure.
+func (mr *MockConfigurerMockRecorder) Configure(saramaConfig interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Setup", reflect.TypeOf((*MockConsumerInterface)(nil).Setup), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Configure", reflect.TypeOf((*MockConfigurer)(nil).Configure), saramaConfig)
 }
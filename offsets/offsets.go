@@ -0,0 +1,83 @@
+// Package offsets looks up the offsets of consumer groups and partitions.
+package offsets
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// Offsets are offsets by topic and partition.
+type Offsets map[string]map[int32]int64
+
+func (offsets Offsets) set(topic string, partition int32, offset int64) {
+	if offsets[topic] == nil {
+		offsets[topic] = map[int32]int64{}
+	}
+
+	offsets[topic][partition] = offset
+}
+
+// Committed gives the offsets committed by the group on the given partitions,
+// being sarama.OffsetNewest (-1) on the ones with no commit yet.
+func Committed(client sarama.Client, group string, partitions map[string][]int32) (Offsets, error) {
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error finding the consumer group coordinator")
+	}
+
+	request := &sarama.OffsetFetchRequest{Version: 1, ConsumerGroup: group}
+	for topic, ids := range partitions {
+		for _, partition := range ids {
+			request.AddPartition(topic, partition)
+		}
+	}
+
+	response, err := coordinator.FetchOffset(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the consumer group offsets")
+	}
+
+	committed := Offsets{}
+	for topic, ids := range partitions {
+		for _, partition := range ids {
+			block := response.GetBlock(topic, partition)
+			if block == nil {
+				committed.set(topic, partition, sarama.OffsetNewest)
+				continue
+			}
+
+			if block.Err != sarama.ErrNoError {
+				return nil, errors.Wrapf(block.Err, "Error fetching the consumer group offset of %s/%d", topic, partition)
+			}
+
+			committed.set(topic, partition, block.Offset)
+		}
+	}
+
+	return committed, nil
+}
+
+// ForTime gives, on each partition, the offset of the first message at or after the
+// given time, or the end of the partition when no message has come since then.
+func ForTime(client sarama.Client, topic string, partitions []int32, at time.Time) (map[int32]int64, error) {
+	offsets := map[int32]int64{}
+	for _, partition := range partitions {
+		offset, err := client.GetOffset(topic, partition, at.UnixNano()/int64(time.Millisecond))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting the offset of %s/%d at %s", topic, partition, at)
+		}
+
+		if offset < 0 {
+			offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error getting the newest offset of %s/%d", topic, partition)
+			}
+		}
+
+		offsets[partition] = offset
+	}
+
+	return offsets, nil
+}
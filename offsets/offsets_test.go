@@ -0,0 +1,59 @@
+package offsets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, handlers map[string]sarama.MockResponse) (sarama.Client, *sarama.MockBroker) {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	handlers["MetadataRequest"] = sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("orders", 0, broker.BrokerID()).
+		SetLeader("orders", 1, broker.BrokerID())
+	handlers["FindCoordinatorRequest"] = sarama.NewMockFindCoordinatorResponse(t).
+		SetCoordinator(sarama.CoordinatorGroup, "group", broker)
+	broker.SetHandlerByMap(handlers)
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	assert.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client, broker
+}
+
+func TestCommittedTellsPartitionsWithoutCommit(t *testing.T) {
+	client, _ := newTestClient(t, map[string]sarama.MockResponse{
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("group", "orders", 0, 42, "", sarama.ErrNoError).
+			SetOffset("group", "orders", 1, -1, "", sarama.ErrNoError),
+	})
+
+	committed, err := Committed(client, "group", map[string][]int32{"orders": {0, 1}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Offsets{"orders": {0: 42, 1: -1}}, committed)
+}
+
+func TestForTimeFallsBackToTheNewestOffset(t *testing.T) {
+	at := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	millis := at.UnixNano() / int64(time.Millisecond)
+	client, _ := newTestClient(t, map[string]sarama.MockResponse{
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, millis, 10).
+			SetOffset("orders", 1, millis, -1).
+			SetOffset("orders", 1, sarama.OffsetNewest, 20),
+	})
+
+	offsets, err := ForTime(client, "orders", []int32{0, 1}, at)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[int32]int64{0: 10, 1: 20}, offsets)
+}
@@ -0,0 +1,200 @@
+package offsets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// ErrGroupActive is returned when moving the offsets of a group which has members,
+// since they would commit over the new offsets.
+var ErrGroupActive = errors.New("the consumer group has active members, stop its consumers before moving its offsets")
+
+// Position is where Plan moves the committed offsets to, see ToTime, ToOffset and ShiftBy.
+type Position struct {
+	time   time.Time
+	offset int64
+	shift  int64
+	kind   string
+}
+
+// ToTime moves each partition to its first message at or after the given time.
+func ToTime(at time.Time) Position {
+	return Position{time: at, kind: "time"}
+}
+
+// ToOffset moves each partition to the given offset, kept within the partition bounds.
+func ToOffset(offset int64) Position {
+	return Position{offset: offset, kind: "offset"}
+}
+
+// ShiftBy moves the committed offset of each partition by the given amount,
+// backwards when negative. Partitions with no committed offset are left out.
+func ShiftBy(shift int64) Position {
+	return Position{shift: shift, kind: "shift"}
+}
+
+func (position Position) String() string {
+	switch position.kind {
+	case "time":
+		return "time " + position.time.Format(time.RFC3339)
+	case "offset":
+		return fmt.Sprintf("offset %d", position.offset)
+	}
+
+	return fmt.Sprintf("shift %+d", position.shift)
+}
+
+// Move is an offset change planned for a partition, From being -1 when nothing is committed.
+type Move struct {
+	Topic     string
+	Partition int32
+	From      int64
+	To        int64
+}
+
+func (move Move) String() string {
+	return fmt.Sprintf("%s/%d: %d -> %d", move.Topic, move.Partition, move.From, move.To)
+}
+
+// Plan tells where the committed offsets of the group on every partition of
+// the topics would be moved to, without moving them.
+func Plan(client sarama.Client, group string, topics []string, position Position) ([]Move, error) {
+	partitions := map[string][]int32{}
+	for _, topic := range topics {
+		ids, err := client.Partitions(topic)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error listing the partitions of %s", topic)
+		}
+
+		partitions[topic] = ids
+	}
+
+	committed, err := Committed(client, group, partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []Move
+	for _, topic := range topics {
+		var atTime map[int32]int64
+		if position.kind == "time" {
+			if atTime, err = ForTime(client, topic, partitions[topic], position.time); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, partition := range partitions[topic] {
+			from := committed[topic][partition]
+
+			var to int64
+			switch position.kind {
+			case "time":
+				to = atTime[partition]
+			case "offset":
+				to = position.offset
+			default:
+				if from < 0 {
+					continue
+				}
+				to = from + position.shift
+			}
+
+			if to, err = bounded(client, topic, partition, to); err != nil {
+				return nil, err
+			}
+
+			moves = append(moves, Move{Topic: topic, Partition: partition, From: from, To: to})
+		}
+	}
+
+	return moves, nil
+}
+
+// bounded keeps the offset between the oldest and the newest offsets of the partition.
+func bounded(client sarama.Client, topic string, partition int32, offset int64) (int64, error) {
+	oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Error getting the oldest offset of %s/%d", topic, partition)
+	}
+
+	newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Error getting the newest offset of %s/%d", topic, partition)
+	}
+
+	if offset < oldest {
+		return oldest, nil
+	}
+
+	if offset > newest {
+		return newest, nil
+	}
+
+	return offset, nil
+}
+
+// Apply commits the planned offsets for the group, refusing with ErrGroupActive
+// while the group has members.
+func Apply(client sarama.Client, group string, moves []Move) error {
+	if err := inactive(client, group); err != nil {
+		return err
+	}
+
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return errors.Wrap(err, "Error finding the consumer group coordinator")
+	}
+
+	request := &sarama.OffsetCommitRequest{
+		Version:                 2,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+		RetentionTime:           -1,
+	}
+	for _, move := range moves {
+		request.AddBlock(move.Topic, move.Partition, move.To, -1, 0, "")
+	}
+
+	response, err := coordinator.CommitOffset(request)
+	if err != nil {
+		return errors.Wrap(err, "Error committing the consumer group offsets")
+	}
+
+	for topic, partitions := range response.Errors {
+		for partition, kerr := range partitions {
+			if kerr != sarama.ErrNoError {
+				return errors.Wrapf(kerr, "Error committing the offset of %s/%d", topic, partition)
+			}
+		}
+	}
+
+	return nil
+}
+
+// inactive fails with ErrGroupActive when the group has members.
+func inactive(client sarama.Client, group string) error {
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return errors.Wrap(err, "Error finding the consumer group coordinator")
+	}
+
+	response, err := coordinator.DescribeGroups(&sarama.DescribeGroupsRequest{Groups: []string{group}})
+	if err != nil {
+		return errors.Wrap(err, "Error describing the consumer group")
+	}
+
+	for _, description := range response.Groups {
+		if description.Err != sarama.ErrNoError {
+			return errors.Wrap(description.Err, "Error describing the consumer group")
+		}
+
+		if len(description.Members) > 0 {
+			return fmt.Errorf("%w, %d members on %s state", ErrGroupActive, len(description.Members), description.State)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package offsets
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOffsetResponse(t *testing.T) *sarama.MockOffsetResponse {
+	return sarama.NewMockOffsetResponse(t).
+		SetOffset("orders", 0, sarama.OffsetOldest, 5).
+		SetOffset("orders", 0, sarama.OffsetNewest, 100).
+		SetOffset("orders", 1, sarama.OffsetOldest, 0).
+		SetOffset("orders", 1, sarama.OffsetNewest, 50)
+}
+
+func newOffsetFetchResponse(t *testing.T) *sarama.MockOffsetFetchResponse {
+	return sarama.NewMockOffsetFetchResponse(t).
+		SetOffset("group", "orders", 0, 42, "", sarama.ErrNoError).
+		SetOffset("group", "orders", 1, -1, "", sarama.ErrNoError)
+}
+
+func TestPlanShiftsCommittedOffsetsWithinBounds(t *testing.T) {
+	client, _ := newTestClient(t, map[string]sarama.MockResponse{
+		"OffsetFetchRequest": newOffsetFetchResponse(t),
+		"OffsetRequest":      newOffsetResponse(t),
+	})
+
+	moves, err := Plan(client, "group", []string{"orders"}, ShiftBy(-40))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Move{{Topic: "orders", Partition: 0, From: 42, To: 5}}, moves)
+}
+
+func TestPlanMovesToOffsetAndTime(t *testing.T) {
+	at := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	millis := at.UnixNano() / int64(time.Millisecond)
+	client, _ := newTestClient(t, map[string]sarama.MockResponse{
+		"OffsetFetchRequest": newOffsetFetchResponse(t),
+		"OffsetRequest": newOffsetResponse(t).
+			SetOffset("orders", 0, millis, 30).
+			SetOffset("orders", 1, millis, 20),
+	})
+
+	toOffset, err := Plan(client, "group", []string{"orders"}, ToOffset(60))
+	assert.Nil(t, err)
+	toTime, err := Plan(client, "group", []string{"orders"}, ToTime(at))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Move{{"orders", 0, 42, 60}, {"orders", 1, -1, 50}}, toOffset)
+	assert.Equal(t, []Move{{"orders", 0, 42, 30}, {"orders", 1, -1, 20}}, toTime)
+}
+
+func TestApplyCommitsOffsetsOfAnEmptyGroup(t *testing.T) {
+	client, broker := newTestClient(t, map[string]sarama.MockResponse{
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("group", &sarama.GroupDescription{GroupId: "group", State: "Empty"}),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	err := Apply(client, "group", []Move{{"orders", 0, 42, 30}})
+
+	assert.Nil(t, err)
+	var commit *sarama.OffsetCommitRequest
+	for _, request := range broker.History() {
+		if request, ok := request.Request.(*sarama.OffsetCommitRequest); ok {
+			commit = request
+		}
+	}
+	assert.NotNil(t, commit)
+	assert.Equal(t, "group", commit.ConsumerGroup)
+}
+
+func TestApplyRefusesActiveGroups(t *testing.T) {
+	client, _ := newTestClient(t, map[string]sarama.MockResponse{
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("group", &sarama.GroupDescription{
+				GroupId: "group",
+				State:   "Stable",
+				Members: map[string]*sarama.GroupMemberDescription{"member-1": {ClientId: "consumer"}},
+			}),
+	})
+
+	err := Apply(client, "group", []Move{{"orders", 0, 42, 30}})
+
+	assert.True(t, errors.Is(err, ErrGroupActive))
+}
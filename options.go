@@ -0,0 +1,78 @@
+package gokafka
+
+import (
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/config"
+)
+
+// RebalanceHook is called with the partitions assigned to, or revoked from, the consumer.
+type RebalanceHook func(session sarama.ConsumerGroupSession, claims map[string][]int32) error
+
+// Option sets up how Handle runs the consumer.
+type Option func(handler *handler)
+
+// OnAssigned calls the hook at the beginning of each session, once the partitions are
+// assigned and before any message is consumed, e.g: to warm per partition caches.
+// An error fails the session setup.
+func OnAssigned(hook RebalanceHook) Option {
+	return func(handler *handler) {
+		handler.onAssigned = append(handler.onAssigned, hook)
+	}
+}
+
+// OnRevoked calls the hook at the end of each session, once the messages of the revoked
+// partitions are consumed and before the offsets are committed for the last time,
+// e.g: to flush per partition buffers.
+func OnRevoked(hook RebalanceHook) Option {
+	return func(handler *handler) {
+		handler.onRevoked = append(handler.onRevoked, hook)
+	}
+}
+
+// WithDrainTimeout is how long in-flight messages are given to be done on shutdown,
+// 25s by default, before they are abandoned.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(handler *handler) {
+		handler.drainTimeout = timeout
+	}
+}
+
+// WithBackoff sets how the failed sessions are restarted, DefaultBackoff by default.
+func WithBackoff(backoff Backoff) Option {
+	return func(handler *handler) {
+		handler.backoff = backoff.withDefaults()
+	}
+}
+
+// OnRestart calls the hook before each restart of a failed session, e.g: to count them on a metric.
+func OnRestart(hook func(restart Restart)) Option {
+	return func(handler *handler) {
+		handler.onRestart = append(handler.onRestart, hook)
+	}
+}
+
+// WithHealth serves the health endpoints of NewHealthHandler on the address, e.g: ":8080",
+// while the consumer runs.
+func WithHealth(addr string, pollTimeout time.Duration) Option {
+	return func(handler *handler) {
+		handler.healthAddr = addr
+		handler.healthPollTimeout = pollTimeout
+	}
+}
+
+// WithSecretRotation checks the secrets resolved by config.DefaultSecrets on each interval,
+// 1m when 0 is given, and once any is rotated rebuilds the config, e.g: with config.Make,
+// restarting the consumer with it after draining the in-flight messages. The previous
+// config is kept when it can't be rebuilt.
+func WithSecretRotation(interval time.Duration, rebuild func() (*config.Config, error)) Option {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	return func(handler *handler) {
+		handler.rotationInterval = interval
+		handler.rebuild = rebuild
+	}
+}
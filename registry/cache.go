@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// cache keeps schemas by ID and by subject/version. When dir is not empty
+// every schema is also written there, to be read back on a cold start.
+type cache struct {
+	dir       string
+	lock      sync.RWMutex
+	byID      map[int]*Schema
+	byVersion map[string]*Schema
+}
+
+func newCache(dir string) *cache {
+	return &cache{
+		dir:       dir,
+		byID:      map[int]*Schema{},
+		byVersion: map[string]*Schema{},
+	}
+}
+
+func (cache *cache) getByID(id int) *Schema {
+	cache.lock.RLock()
+	schema := cache.byID[id]
+	cache.lock.RUnlock()
+	if schema != nil {
+		return schema
+	}
+
+	schema = cache.read(idPath(id))
+	if schema != nil {
+		cache.remember(schema)
+	}
+
+	return schema
+}
+
+func (cache *cache) getByVersion(subject string, version int) *Schema {
+	cache.lock.RLock()
+	schema := cache.byVersion[versionKey(subject, version)]
+	cache.lock.RUnlock()
+	if schema != nil {
+		return schema
+	}
+
+	schema = cache.read(versionPath(subject, fmt.Sprint(version)))
+	if schema != nil {
+		cache.remember(schema)
+	}
+
+	return schema
+}
+
+func (cache *cache) getLatest(subject string) *Schema {
+	return cache.read(versionPath(subject, "latest"))
+}
+
+func (cache *cache) putByID(schema *Schema) {
+	cache.remember(schema)
+	cache.write(idPath(schema.ID), schema)
+}
+
+func (cache *cache) putByVersion(schema *Schema) {
+	cache.remember(schema)
+	cache.write(idPath(schema.ID), schema)
+	cache.write(versionPath(schema.Subject, fmt.Sprint(schema.Version)), schema)
+}
+
+func (cache *cache) putLatest(schema *Schema) {
+	cache.write(versionPath(schema.Subject, "latest"), schema)
+}
+
+func (cache *cache) remember(schema *Schema) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if _, ok := cache.byID[schema.ID]; !ok || len(schema.Subject) > 0 {
+		cache.byID[schema.ID] = schema
+	}
+
+	if len(schema.Subject) > 0 {
+		cache.byVersion[versionKey(schema.Subject, schema.Version)] = schema
+	}
+}
+
+func (cache *cache) read(path string) *Schema {
+	if len(cache.dir) == 0 {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(cache.dir, path))
+	if err != nil {
+		return nil
+	}
+
+	schema := &Schema{}
+	if err := json.Unmarshal(content, schema); err != nil {
+		return nil
+	}
+
+	return schema
+}
+
+// write is best effort, a failure here only means a slower cold start.
+func (cache *cache) write(path string, schema *Schema) {
+	if len(cache.dir) == 0 {
+		return
+	}
+
+	content, err := json.Marshal(schema)
+	if err != nil {
+		return
+	}
+
+	path = filepath.Join(cache.dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	// Write to a temp file and rename, so a reader never sees half a schema.
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".schema-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(content)
+	if closeErr := temp.Close(); err != nil || closeErr != nil {
+		return
+	}
+
+	os.Rename(temp.Name(), path)
+}
+
+func versionKey(subject string, version int) string {
+	return fmt.Sprintf("%s/%d", subject, version)
+}
+
+func idPath(id int) string {
+	return filepath.Join("ids", fmt.Sprintf("%d.json", id))
+}
+
+func versionPath(subject string, version string) string {
+	return filepath.Join("subjects", url.PathEscape(subject), version+".json")
+}
@@ -0,0 +1,181 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"sync"
+
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/pkg/errors"
+	"github.com/riferrei/srclient"
+)
+
+// Schema is a schema as registered on the Schema Registry.
+// Subject and Version are empty when it was fetched only by its ID.
+type Schema struct {
+	ID      int    `json:"id"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Schema  string `json:"schema"`
+}
+
+// Client reads schemas from the Schema Registry keeping them in memory and,
+// when a cache dir is configured, on disk so a cold start survives a registry outage.
+type Client struct {
+	registry *srclient.SchemaRegistryClient
+	cache    *cache
+}
+
+var (
+	shared     = map[string]Provider{}
+	sharedLock sync.Mutex
+)
+
+// Shared returns the schema provider built from the AVRO_SCHEMA_* envs.
+// It is created once and reused by every consumer and producer in the process.
+func Shared() (Provider, error) {
+	return SharedWithPrefix("")
+}
+
+// SharedWithPrefix is Shared for the envs with the given prefix, e.g: ORDERS_AVRO_SCHEMA_URL.
+func SharedWithPrefix(prefix string) (Provider, error) {
+	sharedLock.Lock()
+	defer sharedLock.Unlock()
+
+	if provider, ok := shared[prefix]; ok {
+		return provider, nil
+	}
+
+	avroConfig, err := config.GetAvroWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := NewProvider(avroConfig)
+	if err != nil {
+		return nil, err
+	}
+	shared[prefix] = provider
+
+	return provider, nil
+}
+
+func New(avroConfig config.EnvAvroConfig) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := newTLSConfig(avroConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing Schema Registry TLS config")
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	httpClient := &http.Client{
+		Transport: &retryTransport{
+			next:    &bearerTransport{next: transport, token: avroConfig.Token},
+			retries: avroConfig.Retries,
+			backoff: avroConfig.RetryBackoff,
+			timeout: avroConfig.Timeout,
+		},
+	}
+
+	registry := srclient.CreateSchemaRegistryClientWithOptions(avroConfig.Url, httpClient, 16)
+	if len(avroConfig.Username) > 0 {
+		registry.SetCredentials(avroConfig.Username, avroConfig.Password)
+	}
+	// Caching is done by our own cache, which also knows about the disk.
+	registry.CachingEnabled(false)
+
+	return &Client{
+		registry: registry,
+		cache:    newCache(avroConfig.CacheDir),
+	}, nil
+}
+
+func (client *Client) GetSchemaByID(id int) (*Schema, error) {
+	if schema := client.cache.getByID(id); schema != nil {
+		return schema, nil
+	}
+
+	registered, err := client.registry.GetSchema(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error fetching schema id %d", id)
+	}
+
+	schema := &Schema{ID: id, Schema: registered.Schema()}
+	client.cache.putByID(schema)
+
+	return schema, nil
+}
+
+func (client *Client) GetSchemaByVersion(subject string, version int) (*Schema, error) {
+	if schema := client.cache.getByVersion(subject, version); schema != nil {
+		return schema, nil
+	}
+
+	registered, err := client.registry.GetSchemaByVersion(subject, version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error fetching schema %s version %d", subject, version)
+	}
+
+	schema := &Schema{ID: registered.ID(), Subject: subject, Version: version, Schema: registered.Schema()}
+	client.cache.putByVersion(schema)
+
+	return schema, nil
+}
+
+// GetLatestSchema always asks the registry, since latest may change, and only
+// falls back to the last known latest schema when the registry can't be reached.
+func (client *Client) GetLatestSchema(subject string) (*Schema, error) {
+	registered, err := client.registry.GetLatestSchema(subject)
+	if err != nil {
+		if schema := client.cache.getLatest(subject); schema != nil {
+			return schema, nil
+		}
+
+		return nil, errors.Wrapf(err, "Error fetching latest schema of %s", subject)
+	}
+
+	schema := &Schema{ID: registered.ID(), Subject: subject, Version: registered.Version(), Schema: registered.Schema()}
+	client.cache.putByVersion(schema)
+	client.cache.putLatest(schema)
+
+	return schema, nil
+}
+
+func (client *Client) GetVersions(subject string) ([]int, error) {
+	versions, err := client.registry.GetSchemaVersions(subject)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error fetching versions of %s", subject)
+	}
+
+	return versions, nil
+}
+
+func newTLSConfig(avroConfig config.EnvAvroConfig) (*tls.Config, error) {
+	if len(avroConfig.AuthCertificate) == 0 && len(avroConfig.AuthCa) == 0 {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if len(avroConfig.AuthCertificate) > 0 {
+		certificate, err := tls.X509KeyPair([]byte(avroConfig.AuthCertificate), []byte(avroConfig.AuthKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid AVRO_SCHEMA_CERTIFICATE or AVRO_SCHEMA_KEY")
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	// The registry is verified against the system pool unless a CA is given.
+	if len(avroConfig.AuthCa) == 0 {
+		return tlsConfig, nil
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM([]byte(avroConfig.AuthCa)) {
+		return nil, errors.New("no valid certificate found on AVRO_SCHEMA_CA")
+	}
+	tlsConfig.RootCAs = caCertPool
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const userSchema = `{"type":"record","name":"User","fields":[{"name":"id","type":"string"}]}`
+
+func newRegistryServer(t *testing.T, calls *int32, failures int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(calls, 1)
+		if call <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/schemas/ids/7":
+			fmt.Fprintf(w, `{"schema":%q}`, userSchema)
+		case "/subjects/users-value/versions/latest", "/subjects/users-value/versions/2":
+			fmt.Fprintf(w, `{"subject":"users-value","version":2,"id":7,"schema":%q}`, userSchema)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestConfig(url string) config.EnvAvroConfig {
+	return config.EnvAvroConfig{
+		Url:          url,
+		Timeout:      time.Second,
+		Retries:      3,
+		RetryBackoff: time.Millisecond,
+	}
+}
+
+func TestShouldCacheSchemasById(t *testing.T) {
+	var calls int32
+	server := newRegistryServer(t, &calls, 0)
+	defer server.Close()
+
+	client, err := New(newTestConfig(server.URL))
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		schema, err := client.GetSchemaByID(7)
+		assert.Nil(t, err)
+		assert.Equal(t, userSchema, schema.Schema)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestShouldCacheSchemasByVersionAndId(t *testing.T) {
+	var calls int32
+	server := newRegistryServer(t, &calls, 0)
+	defer server.Close()
+
+	client, _ := New(newTestConfig(server.URL))
+
+	schema, err := client.GetSchemaByVersion("users-value", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, schema.ID)
+
+	_, err = client.GetSchemaByID(7)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestShouldRetryWhenRegistryIsUnavailable(t *testing.T) {
+	var calls int32
+	server := newRegistryServer(t, &calls, 2)
+	defer server.Close()
+
+	client, _ := New(newTestConfig(server.URL))
+
+	schema, err := client.GetLatestSchema("users-value")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, schema.Version)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestShouldGiveUpAfterConfiguredRetries(t *testing.T) {
+	var calls int32
+	server := newRegistryServer(t, &calls, 100)
+	defer server.Close()
+
+	avroConfig := newTestConfig(server.URL)
+	avroConfig.Retries = 1
+	client, _ := New(avroConfig)
+
+	_, err := client.GetSchemaByID(7)
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestShouldGiveEachAttemptItsOwnTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+
+		fmt.Fprintf(w, `{"schema":%q}`, userSchema)
+	}))
+	defer server.Close()
+
+	avroConfig := newTestConfig(server.URL)
+	avroConfig.Timeout = 100 * time.Millisecond
+	client, _ := New(avroConfig)
+
+	schema, err := client.GetSchemaByID(7)
+
+	assert.Nil(t, err)
+	assert.Equal(t, userSchema, schema.Schema)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestShouldSendBearerToken(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		fmt.Fprintf(w, `{"schema":%q}`, userSchema)
+	}))
+	defer server.Close()
+
+	avroConfig := newTestConfig(server.URL)
+	avroConfig.Token = "secret-token"
+	client, _ := New(avroConfig)
+
+	_, err := client.GetSchemaByID(1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer secret-token", authorization)
+}
+
+func TestShouldReadDiskCacheOnColdStart(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "registry")
+	defer os.RemoveAll(dir)
+
+	var calls int32
+	server := newRegistryServer(t, &calls, 0)
+	avroConfig := newTestConfig(server.URL)
+	avroConfig.CacheDir = dir
+
+	warm, _ := New(avroConfig)
+	_, err := warm.GetLatestSchema("users-value")
+	assert.Nil(t, err)
+	server.Close()
+
+	avroConfig.Retries = 0
+	cold, _ := New(avroConfig)
+
+	byID, err := cold.GetSchemaByID(7)
+	assert.Nil(t, err)
+	assert.Equal(t, userSchema, byID.Schema)
+
+	latest, err := cold.GetLatestSchema("users-value")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, latest.Version)
+}
+
+// newTestCertificate creates a self signed PEM certificate and key for the given name.
+func newTestCertificate(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	return string(certificate), string(privateKey)
+}
+
+func TestShouldUseTheSystemCAsForMTLSWithoutCA(t *testing.T) {
+	// Set
+	certificate, key := newTestCertificate(t, "gokafka-client")
+	avroConfig := newTestConfig("https://registry")
+	avroConfig.AuthCertificate = certificate
+	avroConfig.AuthKey = key
+
+	// Actions
+	_, err := New(avroConfig)
+	tlsConfig, tlsErr := newTLSConfig(avroConfig)
+
+	// Assertions
+	assert.Nil(t, err)
+	assert.Nil(t, tlsErr)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Nil(t, tlsConfig.RootCAs)
+}
@@ -0,0 +1,273 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Incompatibility is a change that stops a reader schema from reading
+// data written with a writer schema. Path points to the field, e.g: User.address.street.
+type Incompatibility struct {
+	Path    string
+	Message string
+}
+
+func (incompatibility Incompatibility) String() string {
+	return incompatibility.Path + ": " + incompatibility.Message
+}
+
+// VersionReport is the result of checking a reader schema against one registered version.
+type VersionReport struct {
+	Version           int
+	ID                int
+	Incompatibilities []Incompatibility
+}
+
+// Report is the result of checking a reader schema against every version of a subject.
+type Report struct {
+	Subject  string
+	Versions []VersionReport
+}
+
+func (report *Report) Compatible() bool {
+	for _, version := range report.Versions {
+		if len(version.Incompatibilities) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckSubject checks that the reader schema can read every version registered for the subject.
+func CheckSubject(provider Provider, subject string, reader string) (*Report, error) {
+	versions, err := provider.GetVersions(subject)
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(versions)
+
+	report := &Report{Subject: subject}
+	for _, version := range versions {
+		writer, err := provider.GetSchemaByVersion(subject, version)
+		if err != nil {
+			return nil, err
+		}
+
+		incompatibilities, err := CheckCompatibility(reader, writer.Schema)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error checking %s version %d", subject, version)
+		}
+
+		report.Versions = append(report.Versions, VersionReport{
+			Version:           version,
+			ID:                writer.ID,
+			Incompatibilities: incompatibilities,
+		})
+	}
+
+	return report, nil
+}
+
+// CheckCompatibility lists what stops data written with the writer schema from being
+// read with the reader schema, following the Avro schema resolution rules.
+// An empty list means the reader can read it.
+func CheckCompatibility(reader string, writer string) ([]Incompatibility, error) {
+	readerType, err := parseSchema(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid reader schema")
+	}
+
+	writerType, err := parseSchema(writer)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid writer schema")
+	}
+
+	checker := &compatibilityChecker{seen: map[[2]*avroType]bool{}}
+	checker.check(readerType, writerType, rootPath(readerType))
+
+	return checker.incompatibilities, nil
+}
+
+// promotions lists, for each writer type, the reader types able to read it besides itself.
+var promotions = map[string][]string{
+	"int":    {"long", "float", "double"},
+	"long":   {"float", "double"},
+	"float":  {"double"},
+	"string": {"bytes"},
+	"bytes":  {"string"},
+}
+
+type compatibilityChecker struct {
+	seen              map[[2]*avroType]bool
+	incompatibilities []Incompatibility
+}
+
+func (checker *compatibilityChecker) report(path string, format string, args ...interface{}) {
+	checker.incompatibilities = append(checker.incompatibilities, Incompatibility{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (checker *compatibilityChecker) check(reader *avroType, writer *avroType, path string) {
+	// Recursive records would loop forever, each pair only needs one check.
+	pair := [2]*avroType{reader, writer}
+	if checker.seen[pair] {
+		return
+	}
+	checker.seen[pair] = true
+
+	if writer.kind == "union" {
+		for _, branch := range writer.branches {
+			if !checker.readable(reader, branch) {
+				checker.report(path, "writer union branch %s can't be read as %s", branch, reader)
+			}
+		}
+
+		return
+	}
+
+	if reader.kind == "union" {
+		for _, branch := range reader.branches {
+			if checker.readable(branch, writer) {
+				checker.check(branch, writer, path)
+				return
+			}
+		}
+		checker.report(path, "%s is not one of the reader union types %s", writer, reader)
+
+		return
+	}
+
+	if reader.kind != writer.kind {
+		if !isPromotion(writer.kind, reader.kind) {
+			checker.report(path, "type changed from %s to %s", writer, reader)
+		}
+
+		return
+	}
+
+	switch reader.kind {
+	case "record":
+		checker.checkRecord(reader, writer, path)
+	case "enum":
+		checker.checkEnum(reader, writer, path)
+	case "fixed":
+		if !sameName(reader, writer) {
+			checker.report(path, "fixed name changed from %s to %s", writer.name, reader.name)
+		} else if reader.size != writer.size {
+			checker.report(path, "fixed size changed from %d to %d", writer.size, reader.size)
+		}
+	case "array":
+		checker.check(reader.items, writer.items, path+"[]")
+	case "map":
+		checker.check(reader.values, writer.values, path+"{}")
+	}
+}
+
+func (checker *compatibilityChecker) checkRecord(reader *avroType, writer *avroType, path string) {
+	if !sameName(reader, writer) {
+		checker.report(path, "record name changed from %s to %s", writer.name, reader.name)
+		return
+	}
+
+	for _, field := range reader.fields {
+		fieldPath := path + "." + field.name
+		writerField := findField(writer, field)
+		if writerField == nil {
+			if !field.hasDefault {
+				checker.report(fieldPath, "field added without a default")
+			}
+			continue
+		}
+
+		checker.check(field.typ, writerField.typ, fieldPath)
+	}
+}
+
+func (checker *compatibilityChecker) checkEnum(reader *avroType, writer *avroType, path string) {
+	if !sameName(reader, writer) {
+		checker.report(path, "enum name changed from %s to %s", writer.name, reader.name)
+		return
+	}
+
+	if reader.hasEnumDefault {
+		return
+	}
+
+	symbols := map[string]bool{}
+	for _, symbol := range reader.symbols {
+		symbols[symbol] = true
+	}
+
+	var missing []string
+	for _, symbol := range writer.symbols {
+		if !symbols[symbol] {
+			missing = append(missing, symbol)
+		}
+	}
+
+	if len(missing) > 0 {
+		checker.report(path, "enum symbols %s are missing on the reader", strings.Join(missing, ", "))
+	}
+}
+
+// readable tells if writer can be read with reader, without reporting anything.
+func (checker *compatibilityChecker) readable(reader *avroType, writer *avroType) bool {
+	probe := &compatibilityChecker{seen: map[[2]*avroType]bool{}}
+	for pair := range checker.seen {
+		probe.seen[pair] = true
+	}
+	probe.check(reader, writer, "")
+
+	return len(probe.incompatibilities) == 0
+}
+
+func findField(record *avroType, field *avroField) *avroField {
+	names := append([]string{field.name}, field.aliases...)
+	for _, name := range names {
+		for _, candidate := range record.fields {
+			if candidate.name == name {
+				return candidate
+			}
+		}
+	}
+
+	return nil
+}
+
+func sameName(reader *avroType, writer *avroType) bool {
+	if shortName(reader.name) == shortName(writer.name) {
+		return true
+	}
+
+	for _, alias := range reader.aliases {
+		if alias == writer.name || shortName(alias) == shortName(writer.name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isPromotion(writer string, reader string) bool {
+	for _, promoted := range promotions[writer] {
+		if promoted == reader {
+			return true
+		}
+	}
+
+	return false
+}
+
+func rootPath(typ *avroType) string {
+	if len(typ.name) > 0 {
+		return shortName(typ.name)
+	}
+
+	return typ.kind
+}
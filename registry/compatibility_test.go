@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	userV1 = `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":"int"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BLOCKED"]}}
+	]}`
+	userV2 = `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":"int"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BLOCKED","DELETED"]}},
+		{"name":"email","type":["null","string"],"default":null}
+	]}`
+)
+
+func TestShouldAcceptCompatibleReader(t *testing.T) {
+	reader := `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":"long"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BLOCKED","DELETED"]}},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"next","type":["null","User"],"default":null}
+	]}`
+
+	for _, writer := range []string{userV1, userV2} {
+		incompatibilities, err := CheckCompatibility(reader, writer)
+
+		assert.Nil(t, err)
+		assert.Empty(t, incompatibilities)
+	}
+}
+
+func TestShouldListIncompatibleFieldChanges(t *testing.T) {
+	reader := `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":"string"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BLOCKED"]}},
+		{"name":"email","type":"string"}
+	]}`
+
+	incompatibilities, err := CheckCompatibility(reader, userV2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Incompatibility{
+		{Path: "User.id", Message: "type changed from int to string"},
+		{Path: "User.status", Message: "enum symbols DELETED are missing on the reader"},
+		{Path: "User.email", Message: "writer union branch null can't be read as string"},
+	}, incompatibilities)
+}
+
+func TestShouldReportRenamedRecord(t *testing.T) {
+	incompatibilities, err := CheckCompatibility(`{"type":"record","name":"Customer","fields":[]}`, userV1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Incompatibility{
+		{Path: "Customer", Message: "record name changed from com.example.User to Customer"},
+	}, incompatibilities)
+}
+
+func TestShouldCheckEveryVersionOfSubjectOnStubRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/subjects/users-value/versions":
+			fmt.Fprint(w, `[2,1]`)
+		case "/subjects/users-value/versions/1":
+			fmt.Fprintf(w, `{"subject":"users-value","version":1,"id":10,"schema":%q}`, userV1)
+		case "/subjects/users-value/versions/2":
+			fmt.Fprintf(w, `{"subject":"users-value","version":2,"id":11,"schema":%q}`, userV2)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := New(newTestConfig(server.URL))
+
+	report, err := CheckSubject(client, "users-value", userV1)
+
+	assert.Nil(t, err)
+	assert.False(t, report.Compatible())
+	assert.Len(t, report.Versions, 2)
+	assert.Equal(t, 1, report.Versions[0].Version)
+	assert.Empty(t, report.Versions[0].Incompatibilities)
+	assert.Equal(t, 11, report.Versions[1].ID)
+	assert.Equal(t, "User.status", report.Versions[1].Incompatibilities[0].Path)
+}
+
+func TestShouldRefuseInvalidSchema(t *testing.T) {
+	_, err := CheckCompatibility(`{"type":"record","name":"User","fields":[{"name":"a","type":"Unknown"}]}`, userV1)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Directory is a Provider reading .avsc files from disk, for tests and
+// environments that can't reach the Schema Registry.
+// Files are laid out as <dir>/<subject>/<version>-<id>.avsc, e.g:
+// schemas/users-value/2-7.avsc is the version 2 of users-value, registered with the ID 7.
+type Directory struct {
+	byID      map[int]*Schema
+	byVersion map[string]*Schema
+	latest    map[string]*Schema
+}
+
+func NewDirectory(dir string) (*Directory, error) {
+	directory := &Directory{
+		byID:      map[int]*Schema{},
+		byVersion: map[string]*Schema{},
+		latest:    map[string]*Schema{},
+	}
+
+	subjects, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading schema dir")
+	}
+
+	for _, subject := range subjects {
+		if !subject.IsDir() {
+			continue
+		}
+
+		if err := directory.loadSubject(dir, subject.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	return directory, nil
+}
+
+func (directory *Directory) loadSubject(dir string, name string) error {
+	subject, err := url.PathUnescape(name)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid subject dir %s", name)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, name, "*.avsc"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		version, id, err := parseSchemaFileName(filepath.Base(file))
+		if err != nil {
+			return errors.Wrapf(err, "Invalid schema file %s", file)
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "Error reading schema file %s", file)
+		}
+
+		schema := &Schema{ID: id, Subject: subject, Version: version, Schema: string(content)}
+		if registered, ok := directory.byID[id]; ok && registered.Schema != schema.Schema {
+			return fmt.Errorf("schema id %d is used by both %s and %s", id, registered.Subject, subject)
+		}
+
+		directory.byID[id] = schema
+		directory.byVersion[versionKey(subject, version)] = schema
+		if latest, ok := directory.latest[subject]; !ok || latest.Version < version {
+			directory.latest[subject] = schema
+		}
+	}
+
+	return nil
+}
+
+func (directory *Directory) GetSchemaByID(id int) (*Schema, error) {
+	if schema, ok := directory.byID[id]; ok {
+		return schema, nil
+	}
+
+	return nil, fmt.Errorf("schema id %d not found: %w", id, os.ErrNotExist)
+}
+
+func (directory *Directory) GetSchemaByVersion(subject string, version int) (*Schema, error) {
+	if schema, ok := directory.byVersion[versionKey(subject, version)]; ok {
+		return schema, nil
+	}
+
+	return nil, fmt.Errorf("schema %s version %d not found: %w", subject, version, os.ErrNotExist)
+}
+
+func (directory *Directory) GetLatestSchema(subject string) (*Schema, error) {
+	if schema, ok := directory.latest[subject]; ok {
+		return schema, nil
+	}
+
+	return nil, fmt.Errorf("schema %s not found: %w", subject, os.ErrNotExist)
+}
+
+func (directory *Directory) GetVersions(subject string) ([]int, error) {
+	var versions []int
+	for _, schema := range directory.byVersion {
+		if schema.Subject == subject {
+			versions = append(versions, schema.Version)
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("schema %s not found: %w", subject, os.ErrNotExist)
+	}
+	sort.Ints(versions)
+
+	return versions, nil
+}
+
+func parseSchemaFileName(name string) (version int, id int, err error) {
+	parts := strings.SplitN(strings.TrimSuffix(name, ".avsc"), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.New("expected <version>-<id>.avsc")
+	}
+
+	if version, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, errors.Wrap(err, "invalid version")
+	}
+
+	if id, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, errors.Wrap(err, "invalid id")
+	}
+
+	return version, id, nil
+}
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSchemaFile(t *testing.T, dir string, subject string, name string, schema string) {
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, subject), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, subject, name), []byte(schema), 0644))
+}
+
+func TestShouldLoadSchemasFromDirectory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "schemas")
+	defer os.RemoveAll(dir)
+	writeSchemaFile(t, dir, "users-value", "1-3.avsc", `"string"`)
+	writeSchemaFile(t, dir, "users-value", "2-7.avsc", userSchema)
+
+	directory, err := NewDirectory(dir)
+	assert.Nil(t, err)
+
+	byID, err := directory.GetSchemaByID(3)
+	assert.Nil(t, err)
+	assert.Equal(t, `"string"`, byID.Schema)
+	assert.Equal(t, 1, byID.Version)
+
+	byVersion, err := directory.GetSchemaByVersion("users-value", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, byVersion.ID)
+
+	latest, err := directory.GetLatestSchema("users-value")
+	assert.Nil(t, err)
+	assert.Equal(t, userSchema, latest.Schema)
+
+	_, err = directory.GetSchemaByID(99)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestShouldRefuseInvalidSchemaFileNames(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "schemas")
+	defer os.RemoveAll(dir)
+	writeSchemaFile(t, dir, "users-value", "latest.avsc", userSchema)
+
+	_, err := NewDirectory(dir)
+
+	assert.Error(t, err)
+}
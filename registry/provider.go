@@ -0,0 +1,24 @@
+package registry
+
+import (
+	"github.com/leroy-merlin-br/gokafka/config"
+)
+
+// Provider gives the schemas used to decode framed Avro messages.
+// The Schema Registry Client is one of them, a Directory of .avsc files is another.
+type Provider interface {
+	GetSchemaByID(id int) (*Schema, error)
+	GetSchemaByVersion(subject string, version int) (*Schema, error)
+	GetLatestSchema(subject string) (*Schema, error)
+	GetVersions(subject string) ([]int, error)
+}
+
+// NewProvider reads schemas from AvroConfig.Dir when it is set,
+// otherwise from the Schema Registry on AvroConfig.Url.
+func NewProvider(avroConfig config.EnvAvroConfig) (Provider, error) {
+	if len(avroConfig.Dir) > 0 {
+		return NewDirectory(avroConfig.Dir)
+	}
+
+	return New(avroConfig)
+}
@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// avroType is the parsed form of an Avro schema, with just what is needed
+// to check whether a reader schema can read data written with another one.
+type avroType struct {
+	kind           string
+	name           string
+	aliases        []string
+	fields         []*avroField
+	symbols        []string
+	hasEnumDefault bool
+	items          *avroType
+	values         *avroType
+	size           int
+	branches       []*avroType
+}
+
+type avroField struct {
+	name       string
+	aliases    []string
+	typ        *avroType
+	hasDefault bool
+}
+
+var primitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+type schemaParser struct {
+	names map[string]*avroType
+}
+
+func parseSchema(schema string) (*avroType, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+		return nil, errors.Wrap(err, "Error parsing Avro schema JSON")
+	}
+
+	parser := &schemaParser{names: map[string]*avroType{}}
+
+	return parser.parse(raw, "")
+}
+
+func (parser *schemaParser) parse(raw interface{}, namespace string) (*avroType, error) {
+	switch value := raw.(type) {
+	case string:
+		return parser.reference(value, namespace)
+	case []interface{}:
+		union := &avroType{kind: "union"}
+		for _, branch := range value {
+			typ, err := parser.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, typ)
+		}
+
+		return union, nil
+	case map[string]interface{}:
+		return parser.parseComplex(value, namespace)
+	}
+
+	return nil, fmt.Errorf("invalid Avro schema %v", raw)
+}
+
+func (parser *schemaParser) reference(name string, namespace string) (*avroType, error) {
+	if primitives[name] {
+		return &avroType{kind: name}, nil
+	}
+
+	if typ, ok := parser.names[fullName(name, namespace)]; ok {
+		return typ, nil
+	}
+
+	if typ, ok := parser.names[name]; ok {
+		return typ, nil
+	}
+
+	return nil, fmt.Errorf("unknown Avro type %s", name)
+}
+
+func (parser *schemaParser) parseComplex(value map[string]interface{}, namespace string) (*avroType, error) {
+	kind, ok := value["type"].(string)
+	if !ok {
+		return parser.parse(value["type"], namespace)
+	}
+
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		return parser.parseNamed(kind, value, namespace)
+	case "array":
+		items, err := parser.parse(value["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		return &avroType{kind: "array", items: items}, nil
+	case "map":
+		values, err := parser.parse(value["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		return &avroType{kind: "map", values: values}, nil
+	}
+
+	// Primitives may come with extra attributes, e.g: logicalType.
+	return parser.reference(kind, namespace)
+}
+
+func (parser *schemaParser) parseNamed(kind string, value map[string]interface{}, namespace string) (*avroType, error) {
+	name, _ := value["name"].(string)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("%s without a name", kind)
+	}
+
+	if ns, ok := value["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	name = fullName(name, namespace)
+	namespace = namespaceOf(name)
+
+	typ := &avroType{kind: kind, name: name, aliases: stringList(value["aliases"])}
+	if kind == "error" {
+		typ.kind = "record"
+	}
+	// Registered before the fields are parsed, so records can refer to themselves.
+	parser.names[name] = typ
+
+	switch kind {
+	case "enum":
+		typ.symbols = stringList(value["symbols"])
+		_, typ.hasEnumDefault = value["default"]
+	case "fixed":
+		size, _ := value["size"].(float64)
+		typ.size = int(size)
+	default:
+		fields, _ := value["fields"].([]interface{})
+		for _, rawField := range fields {
+			fieldValue, ok := rawField.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid field on record %s", name)
+			}
+
+			field := &avroField{aliases: stringList(fieldValue["aliases"])}
+			field.name, _ = fieldValue["name"].(string)
+			_, field.hasDefault = fieldValue["default"]
+
+			fieldType, err := parser.parse(fieldValue["type"], namespace)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid field %s.%s", name, field.name)
+			}
+			field.typ = fieldType
+
+			typ.fields = append(typ.fields, field)
+		}
+	}
+
+	return typ, nil
+}
+
+func (typ *avroType) String() string {
+	switch typ.kind {
+	case "record", "enum", "fixed":
+		return typ.kind + " " + typ.name
+	case "array":
+		return "array<" + typ.items.String() + ">"
+	case "map":
+		return "map<" + typ.values.String() + ">"
+	case "union":
+		names := make([]string, len(typ.branches))
+		for i, branch := range typ.branches {
+			names[i] = branch.String()
+		}
+
+		return "union[" + strings.Join(names, ", ") + "]"
+	}
+
+	return typ.kind
+}
+
+func fullName(name string, namespace string) string {
+	if strings.Contains(name, ".") || len(namespace) == 0 {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+func namespaceOf(name string) string {
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		return name[:index]
+	}
+
+	return ""
+}
+
+func shortName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func stringList(raw interface{}) []string {
+	values, _ := raw.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// bearerTransport sends the given token on every request, when there is one.
+type bearerTransport struct {
+	next  http.RoundTripper
+	token string
+}
+
+func (transport *bearerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if len(transport.token) == 0 {
+		return transport.next.RoundTrip(request)
+	}
+
+	request = request.Clone(request.Context())
+	request.Header.Set("Authorization", "Bearer "+transport.token)
+
+	return transport.next.RoundTrip(request)
+}
+
+// retryTransport retries idempotent requests that failed to reach the registry
+// or got a 5xx/429 back, waiting an exponential backoff between attempts.
+// The timeout is given to each attempt, so a slow one doesn't use up the retries.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	backoff time.Duration
+	timeout time.Duration
+}
+
+func (transport *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return transport.attempt(request)
+	}
+
+	wait := transport.backoff
+	for attempt := 0; ; attempt++ {
+		response, err := transport.attempt(request)
+		if attempt >= transport.retries || !shouldRetry(response, err) {
+			return response, err
+		}
+
+		if response != nil {
+			response.Body.Close()
+		}
+
+		select {
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// attempt sends the request once, within the timeout, which lasts until the response body is closed.
+func (transport *retryTransport) attempt(request *http.Request) (*http.Response, error) {
+	if transport.timeout <= 0 {
+		return transport.next.RoundTrip(request)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), transport.timeout)
+	response, err := transport.next.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
+
+	return response, nil
+}
+
+// cancelBody releases the attempt timeout once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	defer body.cancel()
+
+	return body.ReadCloser.Close()
+}
+
+func shouldRetry(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+}
@@ -0,0 +1,103 @@
+package gokafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrTooManyFailures is returned once the sessions failed Backoff.MaxFailures times in a row.
+var ErrTooManyFailures = errors.New("the consumer group sessions failed too many times in a row")
+
+// Backoff is how the failed sessions are restarted, waiting Initial before the first
+// restart and Multiplier times more before each next one, up to Max. Those left
+// zero are taken from DefaultBackoff.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// MaxFailures is how many sessions may fail in a row before giving up, 0 never gives up.
+	MaxFailures int
+}
+
+// DefaultBackoff restarts after 1s, 2s, 4s... up to 1m, giving up after 10 failures in a row.
+var DefaultBackoff = Backoff{
+	Initial:     time.Second,
+	Max:         time.Minute,
+	Multiplier:  2,
+	MaxFailures: 10,
+}
+
+// withDefaults fills the zero, or negative, durations and multiplier from DefaultBackoff,
+// the multiplier being at least 1 so the delay never shrinks.
+func (backoff Backoff) withDefaults() Backoff {
+	if backoff.Initial <= 0 {
+		backoff.Initial = DefaultBackoff.Initial
+	}
+
+	if backoff.Max <= 0 {
+		backoff.Max = DefaultBackoff.Max
+	}
+
+	if backoff.Max < backoff.Initial {
+		backoff.Max = backoff.Initial
+	}
+
+	if backoff.Multiplier == 0 {
+		backoff.Multiplier = DefaultBackoff.Multiplier
+	}
+
+	if backoff.Multiplier < 1 {
+		backoff.Multiplier = 1
+	}
+
+	return backoff
+}
+
+// delay is how long to wait before restarting after the given consecutive failures.
+func (backoff Backoff) delay(failures int) time.Duration {
+	delay := float64(backoff.Initial)
+	for i := 1; i < failures; i++ {
+		delay *= backoff.Multiplier
+		if backoff.Max > 0 && delay >= float64(backoff.Max) {
+			return backoff.Max
+		}
+	}
+
+	return time.Duration(delay)
+}
+
+// Restart tells a failed session is about to be restarted.
+type Restart struct {
+	// Failures is how many sessions failed in a row, this one included.
+	Failures int
+	Delay    time.Duration
+	Err      error
+}
+
+// supervise restarts the failed session, waiting the backoff delay. It gives up with
+// ErrTooManyFailures once there are too many failures in a row.
+func (handler *handler) supervise(ctx context.Context, failures int, err error) error {
+	if handler.backoff.MaxFailures > 0 && failures >= handler.backoff.MaxFailures {
+		return fmt.Errorf("%w, %d failures, the last one: %v", ErrTooManyFailures, failures, err)
+	}
+
+	restart := Restart{Failures: failures, Delay: handler.backoff.delay(failures), Err: err}
+	log.Warn().Err(err).Int("failures", failures).Dur("delay", restart.Delay).Msg("Consumer group session failed, restarting it.")
+	for _, hook := range handler.onRestart {
+		hook(restart)
+	}
+
+	timer := time.NewTimer(restart.Delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package gokafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingGroup is a consumer group whose sessions end with the given errors, in turn.
+// The sessions ending with no error, and the running ones by call, get running first.
+type failingGroup struct {
+	sarama.ConsumerGroup
+	errs    []error
+	running map[int]bool
+	calls   int
+}
+
+func (group *failingGroup) Consume(ctx context.Context, topics []string, consumerHandler sarama.ConsumerGroupHandler) error {
+	err := group.errs[group.calls%len(group.errs)]
+	if err == nil || group.running[group.calls] {
+		handler := consumerHandler.(*handler)
+		handler.lock.Lock()
+		handler.started = true
+		handler.lock.Unlock()
+	}
+	group.calls++
+
+	return err
+}
+
+func TestShouldGrowTheBackoffDelayUpToMax(t *testing.T) {
+	// Set
+	backoff := Backoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+
+	// Actions
+	delays := []time.Duration{backoff.delay(1), backoff.delay(2), backoff.delay(3), backoff.delay(4)}
+
+	// Assertions
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, delays)
+}
+
+func TestShouldFillTheBackoffFromTheDefault(t *testing.T) {
+	// Set
+	handler := newHandler(nil, nil, config.Config{}, []Option{WithBackoff(Backoff{MaxFailures: 5})})
+
+	// Actions
+	backoff := handler.backoff
+
+	// Assertions
+	assert.Equal(t, Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2, MaxFailures: 5}, backoff)
+	assert.Equal(t, time.Second, backoff.delay(1))
+}
+
+func TestShouldNeverShrinkTheBackoffDelay(t *testing.T) {
+	// Set
+	handler := newHandler(nil, nil, config.Config{}, []Option{
+		WithBackoff(Backoff{Initial: time.Second, Max: time.Millisecond, Multiplier: 0.5}),
+	})
+
+	// Actions
+	delays := []time.Duration{handler.backoff.delay(1), handler.backoff.delay(2), handler.backoff.delay(10)}
+
+	// Assertions
+	assert.Equal(t, []time.Duration{time.Second, time.Second, time.Second}, delays)
+}
+
+func TestShouldGiveUpAfterTooManyFailuresInARow(t *testing.T) {
+	// Set
+	var restarts []Restart
+	handler := newHandler(nil, nil, config.Config{}, []Option{
+		WithBackoff(Backoff{Initial: time.Millisecond, Multiplier: 2, MaxFailures: 3}),
+		OnRestart(func(restart Restart) {
+			restarts = append(restarts, restart)
+		}),
+	})
+	worker := NewWorker(&config.Config{Topic: "orders"}, nil)
+	group := &failingGroup{errs: []error{errors.New("brokers down")}}
+
+	// Actions
+	err := worker.consume(context.Background(), group, handler)
+
+	// Assertions
+	assert.True(t, errors.Is(err, ErrTooManyFailures))
+	assert.EqualError(t, err, "the consumer group sessions failed too many times in a row, 3 failures, the last one: brokers down")
+	assert.Equal(t, 3, group.calls)
+	assert.Equal(t, []Restart{
+		{Failures: 1, Delay: time.Millisecond, Err: errors.New("brokers down")},
+		{Failures: 2, Delay: 2 * time.Millisecond, Err: errors.New("brokers down")},
+	}, restarts)
+}
+
+func TestShouldResetTheFailuresOnceASessionGotRunning(t *testing.T) {
+	// Set
+	handler := newHandler(nil, nil, config.Config{}, []Option{
+		WithBackoff(Backoff{Initial: time.Millisecond, Multiplier: 1, MaxFailures: 2}),
+	})
+	worker := NewWorker(&config.Config{Topic: "orders"}, nil)
+	group := &failingGroup{errs: []error{errors.New("brokers down")}, running: map[int]bool{1: true}}
+
+	// Actions
+	err := worker.consume(context.Background(), group, handler)
+
+	// Assertions
+	assert.True(t, errors.Is(err, ErrTooManyFailures))
+	assert.Equal(t, 3, group.calls)
+}
+
+func TestShouldResetTheFailuresOnceASessionSucceeds(t *testing.T) {
+	// Set
+	handler := newHandler(nil, nil, config.Config{}, []Option{
+		WithBackoff(Backoff{Initial: time.Millisecond, Multiplier: 1, MaxFailures: 2}),
+	})
+	worker := NewWorker(&config.Config{Topic: "orders"}, nil)
+	group := &failingGroup{errs: []error{errors.New("brokers down"), nil, errors.New("brokers down"), errors.New("brokers down")}}
+
+	// Actions
+	err := worker.consume(context.Background(), group, handler)
+
+	// Assertions
+	assert.True(t, errors.Is(err, ErrTooManyFailures))
+	assert.Equal(t, 4, group.calls)
+}
+
+func TestShouldCountTheClaimErrorsAsFailures(t *testing.T) {
+	// Set
+	handler := newHandler(nil, nil, config.Config{}, []Option{
+		WithBackoff(Backoff{Initial: time.Millisecond, Multiplier: 1, MaxFailures: 1}),
+	})
+	worker := NewWorker(&config.Config{Topic: "orders"}, nil)
+	handler.failure = errors.New("Invalid Partition")
+
+	// Actions
+	err := worker.consume(context.Background(), &failingGroup{errs: []error{nil}}, handler)
+
+	// Assertions
+	assert.EqualError(t, err, "the consumer group sessions failed too many times in a row, 1 failures, the last one: Invalid Partition")
+}
+
+func TestShouldTellASessionWhichDidMessagesMadeProgress(t *testing.T) {
+	// Set
+	handler := newHandler(nil, nil, config.Config{}, nil)
+	handler.failure = errors.New("Invalid Partition")
+	handler.drainer.handed("orders/0", &sarama.ConsumerMessage{Topic: "orders", Offset: 10})
+	handler.drainer.handed("orders/0", &sarama.ConsumerMessage{Topic: "orders", Offset: 11})
+
+	// Actions
+	progressed, failure := handler.sessionResult()
+	progressedAgain, _ := handler.sessionResult()
+
+	// Assertions
+	assert.True(t, progressed)
+	assert.EqualError(t, failure, "Invalid Partition")
+	assert.False(t, progressedAgain)
+}
@@ -0,0 +1,338 @@
+package gokafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/leroy-merlin-br/gokafka/config"
+	"github.com/leroy-merlin-br/gokafka/consumer"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// State is where a Worker is on its lifecycle.
+type State int
+
+const (
+	// Starting is the state until the first session is set up.
+	Starting State = iota
+	// Running is the state while a session consumes its claims.
+	Running
+	// Rebalancing is the state from the end of a session until the next one, or the stop, e.g: while
+	// OnRevoked hooks run.
+	Rebalancing
+	// Stopping is the state while the in-flight messages are drained on shutdown.
+	Stopping
+	// Stopped is the state once Run has returned.
+	Stopped
+)
+
+func (state State) String() string {
+	switch state {
+	case Starting:
+		return "starting"
+	case Running:
+		return "running"
+	case Rebalancing:
+		return "rebalancing"
+	case Stopping:
+		return "stopping"
+	}
+
+	return "stopped"
+}
+
+// eventsBuffer is how many transitions an Events subscriber may fall behind before they are dropped.
+const eventsBuffer = 16
+
+// Worker runs a consumer on its consumer group, tracking its State.
+type Worker struct {
+	consumerConfig *config.Config
+	consumer       consumer.ConsumerInterface
+	options        []Option
+
+	lock        sync.Mutex
+	state       State
+	subscribers []chan State
+	client      sarama.Client
+	handler     *handler
+	lastErr     error
+}
+
+func NewWorker(consumerConfig *config.Config, consumer consumer.ConsumerInterface, options ...Option) *Worker {
+	return &Worker{
+		consumerConfig: consumerConfig,
+		consumer:       consumer,
+		options:        options,
+	}
+}
+
+// State tells where the worker is on its lifecycle.
+func (worker *Worker) State() State {
+	worker.lock.Lock()
+	defer worker.lock.Unlock()
+
+	return worker.state
+}
+
+// Events subscribes to the state transitions, the channel being closed once the worker
+// is stopped. Transitions are dropped for subscribers too far behind.
+func (worker *Worker) Events() <-chan State {
+	worker.lock.Lock()
+	defer worker.lock.Unlock()
+
+	events := make(chan State, eventsBuffer)
+	if worker.state == Stopped {
+		close(events)
+		return events
+	}
+
+	worker.subscribers = append(worker.subscribers, events)
+
+	return events
+}
+
+// failed keeps the error as the last one, for the worker status.
+func (worker *Worker) failed(err error) {
+	worker.lock.Lock()
+	defer worker.lock.Unlock()
+
+	worker.lastErr = err
+}
+
+func (worker *Worker) transition(state State) {
+	worker.lock.Lock()
+	defer worker.lock.Unlock()
+
+	if worker.state == state || worker.state == Stopped || (worker.state == Stopping && state != Stopped) {
+		return
+	}
+
+	worker.state = state
+	for _, events := range worker.subscribers {
+		select {
+		case events <- state:
+		default:
+		}
+	}
+
+	if state == Stopped {
+		for _, events := range worker.subscribers {
+			close(events)
+		}
+		worker.subscribers = nil
+	}
+}
+
+// Run consumes the config topics until the context is done, restarting the failed
+// sessions with backoff until there are too many failures in a row, see WithBackoff.
+// Once the context is done, fetching stops and the in-flight messages are given the
+// drain timeout to be done before their offsets are committed, see WithDrainTimeout.
+// A worker is meant to be run once.
+func (worker *Worker) Run(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			worker.failed(err)
+		}
+		worker.transition(Stopped)
+	}()
+
+	handler := worker.newHandler()
+	if len(handler.healthAddr) > 0 {
+		defer worker.serveHealth(handler.healthAddr, handler.healthPollTimeout)()
+	}
+
+	var rotated <-chan struct{}
+	if handler.rebuild != nil {
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		rotated = watchSecrets(watchCtx, handler.rotationInterval)
+	}
+
+	for {
+		var rebuilt *config.Config
+		rebuilt, err = worker.runGroup(ctx, handler, rotated)
+		if err != nil || rebuilt == nil {
+			return err
+		}
+
+		worker.lock.Lock()
+		worker.consumerConfig = rebuilt
+		worker.lock.Unlock()
+
+		log.Info().Msg("Restarting the consumer with the rotated secrets.")
+		handler = worker.newHandler()
+	}
+}
+
+// newHandler makes the handler of the next consumer group, its client being set once created.
+func (worker *Worker) newHandler() *handler {
+	handler := newHandler(worker.consumer, nil, *worker.consumerConfig, worker.options)
+	handler.transition = worker.transition
+
+	worker.lock.Lock()
+	worker.handler = handler
+	worker.lock.Unlock()
+
+	return handler
+}
+
+// runGroup consumes on a new client and consumer group until the context is done or, once
+// the secrets are rotated, the config is rebuilt, giving the rebuilt config to run again with.
+func (worker *Worker) runGroup(ctx context.Context, handler *handler, rotated <-chan struct{}) (rebuilt *config.Config, err error) {
+	saramaConfig := configure(worker.consumerConfig.Kafka, worker.consumer)
+
+	kafkaClient, err := sarama.NewClient(strings.Split(worker.consumerConfig.Brokers, ","), saramaConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating Kafka client")
+	}
+	defer kafkaClient.Close()
+
+	group, err := sarama.NewConsumerGroupFromClient(worker.consumerConfig.ConsumerGroup, kafkaClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating consumer group client")
+	}
+	defer func() {
+		// Past the drain timeout the stuck session still holds the group,
+		// whose Close would wait for it, so only the client is closed.
+		if errors.Is(err, ErrDrainTimeout) {
+			go group.Close()
+			return
+		}
+
+		if closeErr := group.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	handler.client = kafkaClient
+
+	worker.lock.Lock()
+	worker.client = kafkaClient
+	worker.lock.Unlock()
+
+	consumeCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	consumed := make(chan error, 1)
+	go func() {
+		consumed <- worker.consume(consumeCtx, group, handler)
+	}()
+
+	for {
+		select {
+		case err := <-consumed:
+			return nil, err
+		case <-ctx.Done():
+			return nil, worker.shutdown(group, handler, cancel, consumed)
+		case <-rotated:
+		}
+
+		next, err := handler.rebuild()
+		if err != nil {
+			log.Error().Err(err).Msg("Error rebuilding the config with the rotated secrets, keeping the previous one.")
+			continue
+		}
+
+		worker.transition(Rebalancing)
+		log.Info().Dur("timeout", handler.drainTimeout).Msg("Draining the in-flight messages to restart with the rotated secrets.")
+
+		return next, worker.drain(group, handler, cancel, consumed)
+	}
+}
+
+// shutdown drains the session once the worker is stopping.
+func (worker *Worker) shutdown(group sarama.ConsumerGroup, handler *handler, cancel context.CancelFunc, consumed <-chan error) error {
+	worker.transition(Stopping)
+	log.Info().Dur("timeout", handler.drainTimeout).Msg("Draining the in-flight messages.")
+
+	return worker.drain(group, handler, cancel, consumed)
+}
+
+// drain stops fetching, lets the in-flight messages be done up to the drain timeout,
+// and ends the session, committing the marked offsets.
+func (worker *Worker) drain(group sarama.ConsumerGroup, handler *handler, cancel context.CancelFunc, consumed <-chan error) error {
+	group.PauseAll()
+	handler.drainer.drain()
+	cancel()
+
+	timer := time.NewTimer(handler.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-consumed:
+		return err
+	case <-timer.C:
+	}
+
+	abandoned := handler.abandon()
+	for _, message := range abandoned {
+		log.Error().Str("topic", message.Topic).Int32("partition", message.Partition).Int64("offset", message.Offset).Msg("Message abandoned on shutdown.")
+	}
+
+	return fmt.Errorf("%w, %d messages abandoned", ErrDrainTimeout, len(abandoned))
+}
+
+// watchSecrets tells about the rotations of the secrets resolved by config.DefaultSecrets,
+// many rotations at once being told once, until the context is done.
+func watchSecrets(ctx context.Context, interval time.Duration) <-chan struct{} {
+	rotated := make(chan struct{}, 1)
+	go config.DefaultSecrets.Watch(ctx, interval, func(ref string) {
+		log.Info().Str("ref", ref).Msg("Secret rotated.")
+
+		select {
+		case rotated <- struct{}{}:
+		default:
+		}
+	})
+
+	return rotated
+}
+
+func (worker *Worker) consume(ctx context.Context, group sarama.ConsumerGroup, handler *handler) error {
+	topics := strings.Split(worker.consumerConfig.Topic, ",")
+	failures := 0
+	for {
+		// `Consume` should be called inside an infinite loop, when a
+		// server-side rebalance happens, the consumer session will need to be
+		// recreated to get the new claims
+		err := group.Consume(ctx, topics, handler)
+		progressed, failure := handler.sessionResult()
+		if err == nil {
+			err = failure
+		}
+
+		// Only failures in a row count, those after a session which made progress start over.
+		if progressed {
+			failures = 0
+		}
+
+		// check if context was cancelled, signaling that the consumer should stop
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return errors.Wrap(err, "Error consuming the consumer group session")
+		}
+
+		worker.transition(Rebalancing)
+		if readiness, ok := worker.consumer.(consumer.Readiness); ok {
+			readiness.SetReady(make(chan bool))
+		}
+
+		if err == nil {
+			continue
+		}
+
+		worker.failed(err)
+		failures++
+		if err := handler.supervise(ctx, failures, err); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package gokafka
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/mock/gomock"
+	"github.com/leroy-merlin-br/gokafka/config"
+	consumerMocks "github.com/leroy-merlin-br/gokafka/consumer/mocks"
+	"github.com/leroy-merlin-br/gokafka/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldPublishTheStateTransitions(t *testing.T) {
+	// Set
+	worker := NewWorker(&config.Config{}, nil)
+	events := worker.Events()
+
+	// Actions
+	worker.transition(Running)
+	worker.transition(Running)
+	worker.transition(Rebalancing)
+	worker.transition(Stopped)
+
+	// Assertions
+	var states []State
+	for state := range events {
+		states = append(states, state)
+	}
+	assert.Equal(t, []State{Running, Rebalancing, Stopped}, states)
+	assert.Equal(t, Stopped, worker.State())
+}
+
+func TestShouldGiveClosedEventsOnceStopped(t *testing.T) {
+	// Set
+	worker := NewWorker(&config.Config{}, nil)
+	worker.transition(Stopped)
+
+	// Actions
+	_, open := <-worker.Events()
+
+	// Assertions
+	assert.False(t, open)
+}
+
+func TestShouldFollowTheSessionsOfTheConsumer(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	session := consumerMocks.NewMockConsumerGroupSession(controller)
+	worker := NewWorker(&config.Config{}, consumer)
+	handler := newHandler(consumer, nil, config.Config{}, nil)
+	handler.transition = worker.transition
+
+	// Expectations
+	consumer.EXPECT().Setup(session).Return(nil)
+	consumer.EXPECT().Cleanup(session).Return(nil)
+
+	// Actions
+	assert.Equal(t, Starting, worker.State())
+	setupErr := handler.Setup(session)
+	running := worker.State()
+	cleanupErr := handler.Cleanup(session)
+
+	// Assertions
+	assert.Nil(t, setupErr)
+	assert.Nil(t, cleanupErr)
+	assert.Equal(t, Running, running)
+	assert.Equal(t, Rebalancing, worker.State())
+}
+
+func TestShouldStopWhenTheClientCantBeCreated(t *testing.T) {
+	// Set
+	controller := gomock.NewController(t)
+	consumer := mocks.NewMockConsumerInterface(controller)
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Metadata.Retry.Max = 0
+	worker := NewWorker(&config.Config{Kafka: saramaConfig, Brokers: "127.0.0.1:1", ConsumerGroup: "group", Topic: "orders"}, consumer)
+	events := worker.Events()
+
+	// Actions
+	err := worker.Run(context.Background())
+
+	// Assertions
+	assert.ErrorContains(t, err, "Error creating Kafka client")
+	assert.Equal(t, Stopped, <-events)
+	assert.Equal(t, Stopped, worker.State())
+}
+
+// sessionsConsumer tells about each session set up, consuming every message.
+type sessionsConsumer struct {
+	setups chan struct{}
+}
+
+func (consumer *sessionsConsumer) Setup(sarama.ConsumerGroupSession) error {
+	consumer.setups <- struct{}{}
+	return nil
+}
+
+func (consumer *sessionsConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (consumer *sessionsConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		session.MarkMessage(message, "")
+	}
+
+	return nil
+}
+
+func TestShouldRestartWithTheRebuiltConfigOnceASecretIsRotated(t *testing.T) {
+	// Set
+	broker := newGroupBroker(t)
+	defer broker.Close()
+
+	var password atomic.Value
+	password.Store("before")
+	config.DefaultSecrets.Register("rotating", config.SecretProviderFunc(func(ref string) (string, error) {
+		return password.Load().(string), nil
+	}))
+	_, err := config.DefaultSecrets.Secret("rotating://kafka_pw")
+	assert.Nil(t, err)
+
+	newConfig := func() *config.Config {
+		saramaConfig := sarama.NewConfig()
+		saramaConfig.Version = sarama.V2_1_0_0
+
+		return &config.Config{Kafka: saramaConfig, Brokers: broker.Addr(), ConsumerGroup: "group", Topic: "orders"}
+	}
+	rebuilt := newConfig()
+	consumer := &sessionsConsumer{setups: make(chan struct{}, 10)}
+	worker := NewWorker(newConfig(), consumer, WithSecretRotation(10*time.Millisecond, func() (*config.Config, error) {
+		return rebuilt, nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan error, 1)
+
+	// Actions
+	go func() {
+		returned <- worker.Run(ctx)
+	}()
+	<-consumer.setups
+	password.Store("after")
+
+	// Assertions
+	select {
+	case <-consumer.setups:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the consumer wasn't restarted once the secret was rotated")
+	}
+
+	worker.lock.Lock()
+	assert.Same(t, rebuilt, worker.consumerConfig)
+	worker.lock.Unlock()
+
+	cancel()
+	assert.Nil(t, <-returned)
+}